@@ -64,7 +64,7 @@ func (c *client) fetchBlockTransactions(context context.Context, bn interface{})
 }
 
 func (c *client) MonitorTransactions(height uint64, callback func(rxgo.Observable) error) error {
-	return callback(c.MonitorBlockHeight(int64(height)).Map(func(_ context.Context, offset interface{}) (interface{}, error) {
+	return callback(c.MonitorBlockHeight(context.Background(), int64(height)).Map(func(_ context.Context, offset interface{}) (interface{}, error) {
 		if offset, Ok := (offset).(int64); Ok {
 			block, err := c.GetBlockByHeight(offset)
 			bn := NewBlockNotification(offset)
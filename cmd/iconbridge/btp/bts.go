@@ -0,0 +1,192 @@
+package btp
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/icon-project/icon-bridge/common/codec"
+)
+
+// BTS service types
+const (
+	BTSRequestCoinTransfer = iota
+	BTSRequestCoinRegister
+	BTSResponseHandleService
+	BTSBlacklistMessage
+	BTSChangeTokenLimit
+	BTSUnknownType
+)
+
+var btsTypeNames = map[int64]string{
+	BTSRequestCoinTransfer:   "transfer",
+	BTSRequestCoinRegister:   "register",
+	BTSResponseHandleService: "response",
+	BTSBlacklistMessage:      "blacklist",
+	BTSChangeTokenLimit:      "token_limit",
+	BTSUnknownType:           "unknown",
+}
+
+// BTS blacklist request types
+const (
+	BTSBlacklistAdd = iota
+	BTSBlacklistRemove
+)
+
+// BTSMessage ...
+// is the payload of a BTP message of the bts service; only the field
+// of its type is set, if its data can be decoded
+type BTSMessage struct {
+	Type       int64              `json:"type"`
+	Data       []byte             `json:"-"`
+	Transfer   *TransferRequest   `json:"transfer,omitempty"`
+	Response   *Response          `json:"response,omitempty"`
+	Blacklist  *BlacklistRequest  `json:"blacklist,omitempty"`
+	TokenLimit *TokenLimitRequest `json:"token_limit,omitempty"`
+}
+
+// Asset ...
+// is a coin amount of a transfer request
+type Asset struct {
+	Name  string   `json:"name"`
+	Value *big.Int `json:"value"`
+}
+
+// TransferRequest ...
+// asks the destination BTS to transfer the assets to "To"
+type TransferRequest struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Assets []*Asset `json:"assets"`
+}
+
+// Response ...
+// answers a request of the source BTS, Code is zero on success
+type Response struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
+
+// BlacklistRequest ...
+// adds or removes addresses of network "Net" from the blacklist
+type BlacklistRequest struct {
+	Type  int64    `json:"type"`
+	Addrs []string `json:"addrs"`
+	Net   string   `json:"net"`
+}
+
+// TokenLimitRequest ...
+// changes the transfer limits of coins on network "Net"
+type TokenLimitRequest struct {
+	CoinNames []string   `json:"coin_names"`
+	Limits    []*big.Int `json:"limits"`
+	Net       string     `json:"net"`
+}
+
+type rawBTSMessage struct {
+	Type []byte
+	Data []byte
+}
+
+type rawAsset struct {
+	Name  string
+	Value []byte
+}
+
+type rawTransferRequest struct {
+	From   string
+	To     string
+	Assets []*rawAsset
+}
+
+type rawResponse struct {
+	Code    []byte
+	Message string
+}
+
+type rawBlacklistRequest struct {
+	Type  []byte
+	Addrs []string
+	Net   string
+}
+
+type rawTokenLimitRequest struct {
+	CoinNames []string
+	Limits    [][]byte
+	Net       string
+}
+
+// DecodeBTSMessage ...
+// decodes a bts payload and its data; the data of an unknown type, or
+// which can't be decoded, is left undecoded without failing
+func DecodeBTSMessage(b []byte) (*BTSMessage, error) {
+	raw := &rawBTSMessage{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, raw); err != nil {
+		return nil, fmt.Errorf("bts message: %v", err)
+	}
+	m := &BTSMessage{Type: intFromBytes(raw.Type).Int64(), Data: raw.Data}
+	switch m.Type {
+	case BTSRequestCoinTransfer:
+		r := &rawTransferRequest{}
+		if _, err := codec.RLP.UnmarshalFromBytes(m.Data, r); err == nil {
+			m.Transfer = &TransferRequest{From: r.From, To: r.To}
+			for _, a := range r.Assets {
+				m.Transfer.Assets = append(m.Transfer.Assets, &Asset{Name: a.Name, Value: intFromBytes(a.Value)})
+			}
+		}
+	case BTSResponseHandleService:
+		r := &rawResponse{}
+		if _, err := codec.RLP.UnmarshalFromBytes(m.Data, r); err == nil {
+			m.Response = &Response{Code: intFromBytes(r.Code).Int64(), Message: r.Message}
+		}
+	case BTSBlacklistMessage:
+		r := &rawBlacklistRequest{}
+		if _, err := codec.RLP.UnmarshalFromBytes(m.Data, r); err == nil {
+			m.Blacklist = &BlacklistRequest{Type: intFromBytes(r.Type).Int64(), Addrs: r.Addrs, Net: r.Net}
+		}
+	case BTSChangeTokenLimit:
+		r := &rawTokenLimitRequest{}
+		if _, err := codec.RLP.UnmarshalFromBytes(m.Data, r); err == nil {
+			m.TokenLimit = &TokenLimitRequest{CoinNames: r.CoinNames, Net: r.Net}
+			for _, l := range r.Limits {
+				m.TokenLimit.Limits = append(m.TokenLimit.Limits, intFromBytes(l))
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *BTSMessage) TypeName() string {
+	if name, ok := btsTypeNames[m.Type]; ok {
+		return name
+	}
+	return fmt.Sprintf("type(%d)", m.Type)
+}
+
+func (m *BTSMessage) summary() string {
+	switch {
+	case m.Transfer != nil:
+		assets := make([]string, 0, len(m.Transfer.Assets))
+		for _, a := range m.Transfer.Assets {
+			assets = append(assets, fmt.Sprintf("%s %v", a.Name, a.Value))
+		}
+		return fmt.Sprintf("%s %s→%s", strings.Join(assets, ", "), m.Transfer.From, m.Transfer.To)
+	case m.Response != nil:
+		return fmt.Sprintf("code=%d %q", m.Response.Code, m.Response.Message)
+	case m.Blacklist != nil:
+		op := "add"
+		if m.Blacklist.Type == BTSBlacklistRemove {
+			op = "remove"
+		}
+		return fmt.Sprintf("%s net=%s %s", op, m.Blacklist.Net, strings.Join(m.Blacklist.Addrs, ","))
+	case m.TokenLimit != nil:
+		limits := make([]string, 0, len(m.TokenLimit.CoinNames))
+		for i, name := range m.TokenLimit.CoinNames {
+			if i < len(m.TokenLimit.Limits) {
+				limits = append(limits, fmt.Sprintf("%s=%v", name, m.TokenLimit.Limits[i]))
+			}
+		}
+		return fmt.Sprintf("net=%s %s", m.TokenLimit.Net, strings.Join(limits, ","))
+	}
+	return ""
+}
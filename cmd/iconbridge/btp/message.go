@@ -0,0 +1,148 @@
+// Package btp decodes the BTP messages carried by chain.Event.Message,
+// and the payloads of the known services, as described in
+// docs/message-format.md
+package btp
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/icon-project/icon-bridge/common/codec"
+)
+
+const (
+	ServiceBMC = "bmc"
+	ServiceBTS = "bts"
+)
+
+// Message ...
+// is a BTP message built by the source BMC
+type Message struct {
+	Src     string   `json:"src"`
+	Dst     string   `json:"dst"`
+	Svc     string   `json:"svc"`
+	Sn      *big.Int `json:"sn"` // negative for an error response
+	Payload []byte   `json:"-"`
+}
+
+type rawMessage struct {
+	Src     string
+	Dst     string
+	Svc     string
+	Sn      []byte
+	Payload []byte
+}
+
+func DecodeMessage(b []byte) (*Message, error) {
+	raw := &rawMessage{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, raw); err != nil {
+		return nil, fmt.Errorf("btp message: %v", err)
+	}
+	return &Message{
+		Src:     raw.Src,
+		Dst:     raw.Dst,
+		Svc:     raw.Svc,
+		Sn:      intFromBytes(raw.Sn),
+		Payload: raw.Payload,
+	}, nil
+}
+
+// ErrorMessage ...
+// is the payload of a BTP message with a negative sn, sent back by
+// the BMC which failed to handle the message
+type ErrorMessage struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
+
+type rawErrorMessage struct {
+	Code    []byte
+	Message string
+}
+
+func DecodeErrorMessage(b []byte) (*ErrorMessage, error) {
+	raw := &rawErrorMessage{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, raw); err != nil {
+		return nil, fmt.Errorf("error message: %v", err)
+	}
+	return &ErrorMessage{Code: intFromBytes(raw.Code).Int64(), Message: raw.Message}, nil
+}
+
+// BMCMessage ...
+// is the payload of an internal BMC message, e.g. Link, Unlink or Sack
+type BMCMessage struct {
+	Type    string `json:"type"`
+	Payload []byte `json:"-"`
+}
+
+func DecodeBMCMessage(b []byte) (*BMCMessage, error) {
+	m := &BMCMessage{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, m); err != nil {
+		return nil, fmt.Errorf("bmc message: %v", err)
+	}
+	return m, nil
+}
+
+// Decoded ...
+// is a BTP message with its payload decoded, if its service is known
+type Decoded struct {
+	*Message
+	Error *ErrorMessage `json:"error,omitempty"`
+	BMC   *BMCMessage   `json:"bmc,omitempty"`
+	BTS   *BTSMessage   `json:"bts,omitempty"`
+}
+
+// Decode ...
+// decodes the BTP message "b" and its payload; a payload that can't be
+// decoded is left undecoded without failing
+func Decode(b []byte) (*Decoded, error) {
+	m, err := DecodeMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	d := &Decoded{Message: m}
+	switch {
+	case m.Sn.Sign() < 0:
+		d.Error, _ = DecodeErrorMessage(m.Payload)
+	case m.Svc == ServiceBMC:
+		d.BMC, _ = DecodeBMCMessage(m.Payload)
+	case m.Svc == ServiceBTS:
+		d.BTS, _ = DecodeBTSMessage(m.Payload)
+	}
+	return d, nil
+}
+
+// String ...
+// summarizes the message for logs,
+// e.g. "bts transfer sn=42 ICX 10 hx..→0x.."
+func (d *Decoded) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s", d.Svc)
+	switch {
+	case d.Error != nil:
+		fmt.Fprintf(&sb, " error sn=%v code=%d %q", d.Sn, d.Error.Code, d.Error.Message)
+	case d.BMC != nil:
+		fmt.Fprintf(&sb, " %s sn=%v", d.BMC.Type, d.Sn)
+	case d.BTS != nil:
+		fmt.Fprintf(&sb, " %s sn=%v", d.BTS.TypeName(), d.Sn)
+		if s := d.BTS.summary(); s != "" {
+			sb.WriteString(" " + s)
+		}
+	default:
+		fmt.Fprintf(&sb, " sn=%v payload=%d bytes", d.Sn, len(d.Payload))
+	}
+	fmt.Fprintf(&sb, " %s→%s", d.Src, d.Dst)
+	return sb.String()
+}
+
+// intFromBytes ...
+// returns the integer of two's complement big-endian bytes,
+// as encoded by the BMC and BTS contracts
+func intFromBytes(b []byte) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return v
+}
@@ -0,0 +1,81 @@
+package btp
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	iconBMC = "btp://0x2.icon/cx0000000000000000000000000000000000000001"
+	bscBMC  = "btp://0x61.bsc/0x034AaDE86BF402F023Aa17E5725fABC4ab9E9798"
+)
+
+func encode(t *testing.T, v interface{}) []byte {
+	b, err := codec.RLP.MarshalToBytes(v)
+	require.NoError(t, err)
+	return b
+}
+
+func TestDecodeTransfer(t *testing.T) {
+	data := encode(t, &rawTransferRequest{
+		From: "hx0000000000000000000000000000000000000002",
+		To:   "0x0000000000000000000000000000000000000003",
+		// unsigned values may be encoded with a leading zero byte
+		Assets: []*rawAsset{{Name: "ICX", Value: []byte{0x00, 0x8a, 0xc7, 0x23, 0x04, 0x89, 0xe8, 0x00, 0x00}}},
+	})
+	payload := encode(t, &rawBTSMessage{Type: []byte{BTSRequestCoinTransfer}, Data: data})
+	b := encode(t, &rawMessage{Src: iconBMC, Dst: bscBMC, Svc: ServiceBTS, Sn: []byte{42}, Payload: payload})
+
+	d, err := Decode(b)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), d.Sn)
+	require.NotNil(t, d.BTS)
+	require.NotNil(t, d.BTS.Transfer)
+	require.Len(t, d.BTS.Transfer.Assets, 1)
+	assert.Equal(t, "10000000000000000000", d.BTS.Transfer.Assets[0].Value.String())
+	assert.Equal(t, "bts transfer sn=42 ICX 10000000000000000000 "+
+		"hx0000000000000000000000000000000000000002→0x0000000000000000000000000000000000000003 "+
+		iconBMC+"→"+bscBMC, d.String())
+}
+
+func TestDecodeResponses(t *testing.T) {
+	payload := encode(t, &rawBTSMessage{
+		Type: []byte{BTSResponseHandleService},
+		Data: encode(t, &rawResponse{Code: []byte{1}, Message: "InvalidAddress"}),
+	})
+	d, err := Decode(encode(t, &rawMessage{Src: bscBMC, Dst: iconBMC, Svc: ServiceBTS, Sn: []byte{42}, Payload: payload}))
+	require.NoError(t, err)
+	require.NotNil(t, d.BTS.Response)
+	assert.Equal(t, int64(1), d.BTS.Response.Code)
+
+	// negative sn is an error sent back by the BMC
+	payload = encode(t, &rawErrorMessage{Code: []byte{0x19}, Message: "NotExistsBSH"})
+	d, err = Decode(encode(t, &rawMessage{Src: bscBMC, Dst: iconBMC, Svc: ServiceBTS, Sn: []byte{0xd6}, Payload: payload}))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(-42), d.Sn)
+	require.NotNil(t, d.Error)
+	assert.Equal(t, "NotExistsBSH", d.Error.Message)
+	assert.Nil(t, d.BTS)
+
+	payload = encode(t, &rawBTSMessage{
+		Type: []byte{BTSChangeTokenLimit},
+		Data: encode(t, &rawTokenLimitRequest{CoinNames: []string{"ICX"}, Limits: [][]byte{{0x03, 0xe8}}, Net: "0x61.bsc"}),
+	})
+	d, err = Decode(encode(t, &rawMessage{Src: iconBMC, Dst: bscBMC, Svc: ServiceBTS, Sn: []byte{7}, Payload: payload}))
+	require.NoError(t, err)
+	require.NotNil(t, d.BTS.TokenLimit)
+	assert.Equal(t, big.NewInt(1000), d.BTS.TokenLimit.Limits[0])
+	assert.Contains(t, d.String(), "bts token_limit sn=7 net=0x61.bsc ICX=1000")
+
+	// undecodable payloads are kept
+	d, err = Decode(encode(t, &rawMessage{Src: iconBMC, Dst: bscBMC, Svc: "xcall", Sn: []byte{1}, Payload: []byte{1, 2}}))
+	require.NoError(t, err)
+	assert.Contains(t, d.String(), "xcall sn=1 payload=2 bytes")
+
+	_, err = Decode([]byte{0x01})
+	assert.Error(t, err)
+}
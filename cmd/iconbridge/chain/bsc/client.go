@@ -66,6 +66,7 @@ type IClient interface {
 	GetBlockReceipts(hash common.Hash) (ethTypes.Receipts, error)
 	GetMedianGasPriceForBlock(ctx context.Context) (gasPrice *big.Int, gasHeight *big.Int, err error)
 	GetChainID() *big.Int
+	Close()
 
 	// ethClient
 	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethTypes.Log, error)
@@ -265,6 +266,12 @@ func (c *Client) GetChainID() *big.Int {
 	return c.chainID
 }
 
+// Close ...
+// closes the rpc connection of the client
+func (c *Client) Close() {
+	c.rpc.Close()
+}
+
 func (c *Client) GetEthClient() *ethclient.Client {
 	return c.eth
 }
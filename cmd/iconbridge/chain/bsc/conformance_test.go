@@ -0,0 +1,245 @@
+package bsc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/icon-project/icon-bridge/cmd/e2etest/chain/bsc/abi/bmcperiphery"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/bsc/mocks"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/chaintest"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/icon-project/icon-bridge/common/wallet"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	conformanceBSC  = chain.BTPAddress("btp://0x61.bsc/0xACBA72f72a56A15dBBFFFD638999Fd80A89A19Cf")
+	conformanceICON = chain.BTPAddress("btp://0x2.icon/cxfa5b7b8d71bf6355bf230ff22e1d4777a630c7ec")
+)
+
+// newFakeNode ...
+// returns a client serving the events of "msg" as bmc logs, in blocks at
+// the heights of its receipts, and empty blocks around them
+func newFakeNode(msg *chain.Message) *mocks.IClient {
+	bmc := ethCommon.HexToAddress(conformanceBSC.ContractAddress())
+	blocks := make(map[uint64]ethTypes.Receipts)
+	events := make(map[uint64]*chain.Event)
+	var last uint64
+	for _, receipt := range msg.Receipts {
+		txr := &ethTypes.Receipt{}
+		for _, e := range receipt.Events {
+			data := make([]byte, 8)
+			binary.BigEndian.PutUint64(data, e.Sequence)
+			txr.Logs = append(txr.Logs, &ethTypes.Log{Address: bmc, Data: data})
+			events[e.Sequence] = e
+		}
+		blocks[receipt.Height] = append(blocks[receipt.Height], txr)
+		last = receipt.Height
+	}
+	header := func(height uint64) *ethTypes.Header {
+		h := &ethTypes.Header{Number: new(big.Int).SetUint64(height)}
+		if _, ok := blocks[height]; ok {
+			h.GasUsed = 1
+		}
+		return h
+	}
+	byHash := make(map[ethCommon.Hash]ethTypes.Receipts)
+	for height, receipts := range blocks {
+		byHash[header(height).Hash()] = receipts
+	}
+
+	cl := new(mocks.IClient)
+	cl.On("GetBlockNumber").Return(last+2+BlockFinalityConfirmations, nil)
+	cl.On("GetHeaderByHeight", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *big.Int) *ethTypes.Header {
+			return header(height.Uint64())
+		}, nil)
+	cl.On("FilterLogs", mock.Anything, mock.Anything).Return([]ethTypes.Log{{Address: bmc}}, nil)
+	cl.On("GetBlockReceipts", mock.Anything).Return(
+		func(hash ethCommon.Hash) ethTypes.Receipts {
+			return byHash[hash]
+		}, nil)
+	cl.On("ParseMessage", mock.Anything).Return(
+		func(l ethTypes.Log) *bmcperiphery.BmcperipheryMessage {
+			e := events[binary.BigEndian.Uint64(l.Data)]
+			return &bmcperiphery.BmcperipheryMessage{
+				Next: conformanceICON.String(),
+				Seq:  new(big.Int).SetUint64(e.Sequence),
+				Msg:  e.Message,
+			}
+		}, nil)
+	cl.On("Close").Return()
+	return cl
+}
+
+// newConformanceSender ...
+// returns a sender relaying from icon to a fake node, which prices and nonces txs
+func newConformanceSender(t *testing.T, cl *mocks.IClient, sizeLimit uint64) *sender {
+	privKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	cl.On("GetMedianGasPriceForBlock", mock.Anything).Return(big.NewInt(117), big.NewInt(1), nil)
+	cl.On("GetChainID").Return(big.NewInt(97))
+	cl.On("SuggestGasPrice", mock.Anything).Return(big.NewInt(117), nil)
+	cl.On("Log").Return(log.New())
+	cl.On("NonceAt", mock.Anything, mock.Anything, mock.Anything).Return(uint64(1), nil)
+	cl.On("Close").Return()
+	return &sender{
+		cls:          []IClient{cl},
+		dst:          conformanceBSC,
+		src:          conformanceICON,
+		opts:         senderOptions{GasLimit: 24000000, TxDataSizeLimit: sizeLimit, BoostGasPrice: 1},
+		w:            &wallet.EvmWallet{Skey: privKey, Pkey: &privKey.PublicKey},
+		prevGasPrice: big.NewInt(100),
+		log:          log.New(),
+	}
+}
+
+// sentTx ...
+// returns a relay tx sent to a fake node, which includes it in a block with
+// "txr", or simulates its call returning "data"
+func sentTx(ctx context.Context, t *testing.T, txr *ethTypes.Receipt, data []byte) (chain.RelayTx, error) {
+	cl := new(mocks.IClient)
+	s := newConformanceSender(t, cl, 0)
+	pending := ethTypes.NewTransaction(1, ethCommon.HexToAddress(conformanceBSC.ContractAddress()),
+		big.NewInt(0), 24000000, big.NewInt(117), nil)
+	cl.On("HandleRelayMessage", mock.Anything, mock.Anything, mock.Anything).Return(pending, nil)
+	cl.On("TransactionByHash", mock.Anything, pending.Hash()).Return(pending, false, nil)
+	cl.On("TransactionReceipt", mock.Anything, pending.Hash()).Return(txr, nil)
+	cl.On("CallContract", mock.Anything, mock.Anything, mock.Anything).Return(data, nil)
+	tx, _, err := s.Segment(ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return tx, tx.Send(ctx)
+}
+
+// revertData ...
+// returns the result of a call reverted with "reason"
+func revertData(reason string) []byte {
+	data := make([]byte, 4+32+32, 4+32+32+len(reason))
+	binary.BigEndian.PutUint64(data[4+32+24:], uint64(len(reason)))
+	return append(data, reason...)
+}
+
+func TestConformance_Segment(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceICON, 100, 10, 20, 3, 100)
+	for name, opts := range map[string]chain.SegmentOptions{
+		"default":     {},
+		"maxReceipts": {MaxReceipts: 2},
+		"maxSize":     {MaxSize: 512},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newConformanceSender(t, new(mocks.IClient), 2048)
+			txs := chaintest.Segment(t, s, msg, opts)
+			require.Greater(t, len(txs), 1)
+		})
+	}
+}
+
+func TestConformance_Subscribe(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceBSC, 100, 10, 5, 3, 10)
+	r := &receiver{
+		cls:  []IClient{newFakeNode(msg)},
+		log:  log.New(),
+		opts: ReceiverOptions{SyncConcurrency: 4},
+		src:  conformanceBSC,
+		dst:  conformanceICON,
+	}
+	msgs := chaintest.Subscribe(t, r, chain.SubscribeOptions{Seq: 11, Height: 100}, 13, time.Minute)
+	receipts := chaintest.Receipts(msgs)
+	require.NotEmpty(t, receipts)
+	require.Equal(t, uint64(12), receipts[0].Events[0].Sequence)
+	chaintest.Close(t, r)
+}
+
+func TestConformance_Errors(t *testing.T) {
+	chaintest.Errors(t, []chaintest.ErrorCase{
+		{
+			Name: "InsufficientBalance",
+			Call: func(ctx context.Context) error {
+				cl := new(mocks.IClient)
+				s := newConformanceSender(t, cl, 0)
+				cl.On("HandleRelayMessage", mock.Anything, mock.Anything, mock.Anything).Return(
+					nil, errors.New("insufficient funds for gas * price + value"))
+				tx, _, err := s.Segment(ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				return tx.Send(ctx)
+			},
+			Want: chain.ErrInsufficientBalance,
+		},
+		{
+			Name: "BlockGasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				cl := new(mocks.IClient)
+				s := newConformanceSender(t, cl, 0)
+				cl.On("HandleRelayMessage", mock.Anything, mock.Anything, mock.Anything).Return(
+					nil, errors.New("exceeds block gas limit"))
+				tx, _, err := s.Segment(ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				return tx.Send(ctx)
+			},
+			Want: chain.ErrBlockGasLimitExceeded,
+		},
+		{
+			Name: "GasLimitCeilingReached",
+			Call: func(ctx context.Context) error {
+				tx, _, err := newConformanceSender(t, new(mocks.IClient), 0).Segment(
+					ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				for {
+					if _, err := tx.IncreaseGasLimit(); err != nil {
+						return err
+					}
+				}
+			},
+			Want: chain.ErrGasLimitCeilingReached,
+		},
+		{
+			Name: "GasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				tx, err := sentTx(ctx, t, &ethTypes.Receipt{
+					GasUsed:           24000000,
+					CumulativeGasUsed: 30000000,
+					BlockNumber:       big.NewInt(1),
+				}, nil)
+				if err != nil {
+					return err
+				}
+				_, err = tx.Receipt(ctx)
+				return err
+			},
+			Want: chain.ErrGasLimitExceeded,
+		},
+		{
+			Name: "Revert",
+			Call: func(ctx context.Context) error {
+				tx, err := sentTx(ctx, t, &ethTypes.Receipt{
+					GasUsed:           100000,
+					CumulativeGasUsed: 100000,
+					BlockNumber:       big.NewInt(1),
+				}, revertData("InvalidSeqNumber"))
+				if err != nil {
+					return err
+				}
+				_, err = tx.Receipt(ctx)
+				return err
+			},
+			Want: chain.ErrBMCRevertInvalidSeqNumber,
+		},
+	})
+}
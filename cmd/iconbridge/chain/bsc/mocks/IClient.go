@@ -51,6 +51,11 @@ func (_m *IClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block
 	return r0, r1
 }
 
+// Close provides a mock function with given fields:
+func (_m *IClient) Close() {
+	_m.Called()
+}
+
 // FilterLogs provides a mock function with given fields: ctx, q
 func (_m *IClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
 	ret := _m.Called(ctx, q)
@@ -78,6 +78,7 @@ type receiver struct {
 	dst  chain.BTPAddress
 	opts ReceiverOptions
 	cls  []IClient
+	subs chain.Subscriptions
 }
 
 func (r *receiver) client() IClient {
@@ -467,9 +468,10 @@ func (r *receiver) Subscribe(
 
 	_errCh := make(chan error)
 
-	go func() {
+	if err := r.subs.Go(ctx, func(ctx context.Context) {
 		defer close(_errCh)
 		lastHeight := opts.Height - 1
+		var scan chain.ScanReport
 		if err := r.receiveLoop(ctx,
 			&BnOptions{
 				StartHeight: opts.Height,
@@ -502,20 +504,39 @@ func (r *receiver) Subscribe(
 					}
 					receipt.Events = events
 				}
-				if len(receipts) > 0 {
-					msgCh <- &chain.Message{Receipts: receipts}
+				if msg := scan.Message(v.Height.Uint64(), receipts); msg != nil {
+					select {
+					case msgCh <- msg:
+					case <-ctx.Done():
+						return nil
+					}
 				}
 				lastHeight++
 				return nil
 			}); err != nil {
 			r.log.Errorf("receiveLoop terminated: %v", err)
-			_errCh <- err
+			select {
+			case _errCh <- err:
+			case <-ctx.Done():
+			}
 		}
-	}()
+	}); err != nil {
+		return nil, err
+	}
 
 	return _errCh, nil
 }
 
+// Close ...
+// terminates the subscriptions and closes the rpc clients
+func (r *receiver) Close() error {
+	r.subs.Close()
+	for _, cl := range r.cls {
+		cl.Close()
+	}
+	return nil
+}
+
 func (r *receiver) getRelayReceipts(v *types.BlockNotification) []*chain.Receipt {
 	sc := common.HexToAddress(r.src.ContractAddress())
 	var receipts []*chain.Receipt
@@ -200,7 +200,7 @@ func TestSender_NewObj(t *testing.T) {
 			Events: []*chain.Event{},
 		}},
 	}
-	tx, _, err := s.Segment(context.TODO(), msg)
+	tx, _, err := s.Segment(context.TODO(), msg, chain.SegmentOptions{})
 	require.NoError(t, err)
 	err = tx.Send(context.TODO())
 	require.Equal(t, err.Error(), "InsufficientBalance")
@@ -688,7 +688,7 @@ func TestSender_MockSegment(t *testing.T) {
 			Events: []*chain.Event{},
 		}},
 	}
-	tx, _, err := s.Segment(context.Background(), msg)
+	tx, _, err := s.Segment(context.Background(), msg, chain.SegmentOptions{})
 	require.NoError(t, err)
 	err = tx.Send(context.TODO())
 	require.Equal(t, err.Error(), "not implemented")
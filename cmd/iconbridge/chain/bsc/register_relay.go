@@ -5,4 +5,9 @@ import "github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
 func init() {
 	relay.Senders["bsc"] = NewSender
 	relay.Receivers["bsc"] = NewReceiver
+	relay.LoopDefaults["bsc"] = relay.LoopConfig{
+		TickerInterval:        6, // 3s blocks
+		TxReceiptWaitInterval: 1.5,
+		TxReceiptMaxRetries:   40,
+	}
 }
@@ -45,11 +45,15 @@ const (
 	maxGasPriceBoost     = 10.0
 	defaultReadTimeout   = 50 * time.Second //
 	DefaultGasLimit      = 25000000
+
+	gasLimitIncreaseScale   = 1.25 // raise gas limit by 25% when a relay tx runs out of gas
+	defaultMaxGasLimitScale = 2    // default ceiling is twice the configured gas limit
 )
 
 
 type senderOptions struct {
 	GasLimit         uint64         `json:"gas_limit"`
+	MaxGasLimit      uint64         `json:"max_gas_limit"`
 	TxDataSizeLimit  uint64         `json:"tx_data_size_limit"`
 	BoostGasPrice    float64        `json:"boost_gas_price"`
 	BalanceThreshold intconv.BigInt `json:"balance_threshold"`
@@ -63,6 +67,8 @@ type sender struct {
 	opts         senderOptions
 	cls          []IClient
 	prevGasPrice *big.Int
+
+	nonces chain.NonceTracker
 }
 
 func (s *sender) client() IClient {
@@ -122,7 +128,7 @@ func (s *sender) Status(ctx context.Context) (*chain.BMCLinkStatus, error) {
 
 // Segment ...
 func (s *sender) Segment(
-	ctx context.Context, msg *chain.Message,
+	ctx context.Context, msg *chain.Message, opts chain.SegmentOptions,
 ) (tx chain.RelayTx, newMsg *chain.Message, err error) {
 	if ctx.Err() != nil {
 		return nil, msg, ctx.Err()
@@ -142,11 +148,19 @@ func (s *sender) Segment(
 
 	var msgSize uint64
 
+	txSizeLimit := s.opts.TxDataSizeLimit
+	if opts.MaxSize > 0 && opts.MaxSize < txSizeLimit {
+		txSizeLimit = opts.MaxSize
+	}
+
 	newMsg = &chain.Message{
-		From:     msg.From,
-		Receipts: msg.Receipts,
+		From: msg.From,
 	}
 	for i, receipt := range msg.Receipts {
+		if opts.MaxReceipts > 0 && uint64(i) >= opts.MaxReceipts {
+			newMsg.Receipts = msg.Receipts[i:]
+			break
+		}
 		rlpEvents, err := codec.RLP.MarshalToBytes(receipt.Events)
 		if err != nil {
 			return nil, nil, err
@@ -160,7 +174,7 @@ func (s *sender) Segment(
 			return nil, nil, err
 		}
 		newMsgSize := msgSize + uint64(len(rlpReceipt))
-		if newMsgSize > s.opts.TxDataSizeLimit {
+		if newMsgSize > txSizeLimit {
 			newMsg.Receipts = msg.Receipts[i:]
 			break
 		}
@@ -191,11 +205,38 @@ func (s *sender) Segment(
 	return tx, newMsg, nil
 }
 
+// ResetPipeline ...
+// lets the next relay tx take the account nonce of the latest block again
+func (s *sender) ResetPipeline() {
+	s.nonces.Reset()
+}
+
 func (s *sender) Balance(ctx context.Context) (balance, threshold *big.Int, err error) {
 	bal, err := s.client().GetBalance(ctx, s.w.Address())
 	return bal, &s.opts.BalanceThreshold.Int, err
 }
 
+// Close ...
+// closes the rpc clients
+func (s *sender) Close() error {
+	for _, cl := range s.cls {
+		cl.Close()
+	}
+	return nil
+}
+
+// maxGasLimit ...
+// returns the ceiling up to which the gas limit of a relay tx can be raised
+func (s *sender) maxGasLimit(gasLimit uint64) uint64 {
+	if s.opts.MaxGasLimit == 0 {
+		return gasLimit * defaultMaxGasLimitScale
+	}
+	if s.opts.MaxGasLimit < gasLimit {
+		return gasLimit
+	}
+	return s.opts.MaxGasLimit
+}
+
 func (s *sender) newRelayTx(ctx context.Context, prev string, message []byte, gasPrice *big.Int) (*relayTx, error) {
 	client := s.client()
 
@@ -221,10 +262,12 @@ func (s *sender) newRelayTx(ctx context.Context, prev string, message []byte, ga
 	}
 	txOpts.GasPrice = gasPrice
 	return &relayTx{
-		Prev:    prev,
-		Message: message, // base64.URLEncoding.EncodeToString(rlpCrm),
-		opts:    txOpts,
-		cl:      client,
+		Prev:        prev,
+		Message:     message, // base64.URLEncoding.EncodeToString(rlpCrm),
+		opts:        txOpts,
+		maxGasLimit: s.maxGasLimit(txOpts.GasLimit),
+		cl:          client,
+		nonces:      &s.nonces,
 	}, nil
 }
 
@@ -232,9 +275,16 @@ type relayTx struct {
 	Prev    string `json:"_prev"`
 	Message []byte `json:"_msg"`
 
-	opts      *bind.TransactOpts
-	pendingTx *ethtypes.Transaction
-	cl        IClient
+	opts        *bind.TransactOpts
+	maxGasLimit uint64
+	pendingTx   *ethtypes.Transaction
+	cl          IClient
+	nonces      *chain.NonceTracker
+	cost        *chain.TxCost
+}
+
+func (tx *relayTx) Size() int {
+	return len(tx.Message)
 }
 
 func (tx *relayTx) ID() interface{} {
@@ -244,6 +294,19 @@ func (tx *relayTx) ID() interface{} {
 	return nil
 }
 
+func (tx *relayTx) IncreaseGasLimit() (gasLimit uint64, err error) {
+	if tx.opts.GasLimit >= tx.maxGasLimit {
+		return tx.opts.GasLimit, chain.ErrGasLimitCeilingReached
+	}
+	gasLimit = uint64(float64(tx.opts.GasLimit) * gasLimitIncreaseScale)
+	if gasLimit > tx.maxGasLimit {
+		gasLimit = tx.maxGasLimit
+	}
+	tx.opts.GasLimit = gasLimit
+	tx.pendingTx = nil
+	return gasLimit, nil
+}
+
 func (tx *relayTx) Send(ctx context.Context) (err error) {
 	tx.cl.Log().WithFields(log.Fields{
 		"prev": tx.Prev}).Debug("handleRelayMessage: send tx")
@@ -256,6 +319,7 @@ func (tx *relayTx) Send(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
+	nonce = tx.nonces.Next(nonce)
 	txOpts.Nonce = (&big.Int{}).SetUint64(nonce)
 	defer func() {
 		if tx.pendingTx != nil {
@@ -266,12 +330,10 @@ func (tx *relayTx) Send(ctx context.Context) (err error) {
 	}()
 	tx.pendingTx, err = tx.cl.HandleRelayMessage(&txOpts, tx.Prev, tx.Message)
 	if err != nil {
+		tx.nonces.Release(nonce)
 		tx.cl.Log().WithFields(log.Fields{
 			"error": err}).Debug("handleRelayMessage: send tx")
-		if err.Error() == "insufficient funds for gas * price + value" {
-			return chain.ErrInsufficientBalance
-		}
-		return err
+		return chain.EVMSendError(err)
 	}
 	// tx.cl.Log().WithFields(log.Fields{
 	// 	"txh": tx.pendingTx.Hash(),
@@ -283,6 +345,7 @@ func (tx *relayTx) Receipt(ctx context.Context) (blockNumber uint64, err error)
 	if tx.pendingTx == nil {
 		return 0, fmt.Errorf("no pending tx")
 	}
+	tx.cost = nil
 
 	for i, isPending := 0, true; i < 5 && (isPending || err == ethereum.NotFound); i++ {
 		time.Sleep(time.Second)
@@ -299,26 +362,27 @@ func (tx *relayTx) Receipt(ctx context.Context) (blockNumber uint64, err error)
 	if err != nil {
 		return 0, err
 	}
+	tx.cost = chain.NewTxCost(txr.GasUsed, tx.pendingTx.GasPrice())
 
 	if txr.Status == 0 {
-		callMsg := ethereum.CallMsg{
-			From:       tx.opts.From,
-			To:         tx.pendingTx.To(),
-			Gas:        tx.pendingTx.Gas(),
-			GasPrice:   tx.pendingTx.GasPrice(),
-			Value:      tx.pendingTx.Value(),
-			AccessList: tx.pendingTx.AccessList(),
-			Data:       tx.pendingTx.Data(),
-		}
-
-		_ctx, cancel := context.WithTimeout(ctx, defaultReadTimeout)
-		defer cancel()
-		data, err := tx.cl.CallContract(_ctx, callMsg, txr.BlockNumber)
-		if err != nil {
-			return 0, err
-		}
-
-		return 0, chain.RevertError(revertReason(data))
+		return 0, chain.EVMReceiptError(txr.GasUsed, tx.pendingTx.Gas(), func() (string, error) {
+			callMsg := ethereum.CallMsg{
+				From:       tx.opts.From,
+				To:         tx.pendingTx.To(),
+				Gas:        tx.pendingTx.Gas(),
+				GasPrice:   tx.pendingTx.GasPrice(),
+				Value:      tx.pendingTx.Value(),
+				AccessList: tx.pendingTx.AccessList(),
+				Data:       tx.pendingTx.Data(),
+			}
+			_ctx, cancel := context.WithTimeout(ctx, defaultReadTimeout)
+			defer cancel()
+			data, err := tx.cl.CallContract(_ctx, callMsg, txr.BlockNumber)
+			if err != nil {
+				return "", err
+			}
+			return revertReason(data), nil
+		})
 	}
 
 	tx.cl.Log().WithFields(log.Fields{
@@ -327,6 +391,10 @@ func (tx *relayTx) Receipt(ctx context.Context) (blockNumber uint64, err error)
 	return txr.BlockNumber.Uint64(), nil
 }
 
+func (tx *relayTx) Cost() *chain.TxCost {
+	return tx.cost
+}
+
 func revertReason(data []byte) string {
 	if len(data) < 4+32+32 {
 		return ""
@@ -0,0 +1,69 @@
+package bsc
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/bsc/mocks"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/chaintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender_IncreaseGasLimit(t *testing.T) {
+	tx := &relayTx{
+		opts:        &bind.TransactOpts{GasLimit: 1000},
+		maxGasLimit: 2000,
+		pendingTx:   ethtypes.NewTx(&ethtypes.LegacyTx{}),
+	}
+	for _, want := range []uint64{1250, 1562, 1952, 2000} {
+		got, err := tx.IncreaseGasLimit()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Equal(t, want, tx.opts.GasLimit)
+		require.Nil(t, tx.pendingTx, "tx must be sent again")
+	}
+	got, err := tx.IncreaseGasLimit()
+	require.ErrorIs(t, err, chain.ErrGasLimitCeilingReached)
+	require.Equal(t, uint64(2000), got)
+}
+
+func TestSender_ResetPipeline(t *testing.T) {
+	ctx := context.Background()
+	onchain, nonces := uint64(1), []uint64{}
+	cl := new(mocks.IClient)
+	cl.On("NonceAt", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(context.Context, ethCommon.Address, *big.Int) uint64 {
+			return onchain
+		}, nil)
+	cl.On("HandleRelayMessage", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(opts *bind.TransactOpts, _prev string, _msg []byte) *ethtypes.Transaction {
+			nonces = append(nonces, opts.Nonce.Uint64())
+			return ethtypes.NewTransaction(opts.Nonce.Uint64(), ethCommon.Address{},
+				big.NewInt(0), opts.GasLimit, opts.GasPrice, nil)
+		}, nil)
+	s := newConformanceSender(t, cl, 0)
+	send := func() {
+		tx, _, err := s.Segment(ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+		require.NoError(t, err)
+		require.NoError(t, tx.Send(ctx))
+	}
+
+	// the txs in flight take consecutive nonces
+	send()
+	send()
+	send()
+	require.Equal(t, []uint64{1, 2, 3}, nonces)
+
+	// the second one fails, and the relay rolls back the third: the next tx
+	// takes the nonce of the failed one from the chain
+	onchain = 2
+	s.ResetPipeline()
+	send()
+	require.Equal(t, []uint64{1, 2, 3, 2}, nonces)
+}
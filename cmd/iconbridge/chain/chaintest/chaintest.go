@@ -0,0 +1,255 @@
+// Package chaintest ...
+// checks that the chain.Sender and chain.Receiver of an adapter honour the
+// contract the relay relies on; adapters run it in their tests against a
+// fake node, e.g. a mocked client
+package chaintest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+)
+
+// NewMessage ...
+// returns a message of "receipts" receipts, one per block from "height",
+// each with "events" events of "size" bytes numbered from "seq"
+func NewMessage(from chain.BTPAddress, height, seq uint64, receipts, events, size int) *chain.Message {
+	msg := &chain.Message{From: from}
+	for i := 0; i < receipts; i++ {
+		receipt := &chain.Receipt{Height: height + uint64(i)}
+		for j := 0; j < events; j++ {
+			receipt.Events = append(receipt.Events, &chain.Event{
+				Sequence: seq,
+				Message:  payload(seq, size),
+			})
+			seq++
+		}
+		msg.Receipts = append(msg.Receipts, receipt)
+	}
+	return msg
+}
+
+// payload ...
+// returns "size" bytes, distinct for each sequence
+func payload(seq uint64, size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(seq + uint64(i))
+	}
+	return b
+}
+
+// Segment ...
+// segments "msg" with "s" until nothing is left, and checks that each call
+// takes at least one event and no more than "opts.MaxReceipts" receipts,
+// and returns the events it didn't take as "newMsg", whole and in order;
+// returns the relay txs
+func Segment(t *testing.T, s chain.Sender, msg *chain.Message, opts chain.SegmentOptions) []chain.RelayTx {
+	t.Helper()
+	ctx := context.Background()
+	var txs []chain.RelayTx
+	rest := msg
+	for len(rest.Receipts) > 0 {
+		tx, newMsg, err := s.Segment(ctx, rest, opts)
+		if err != nil {
+			t.Fatalf("Segment: %v", err)
+		}
+		if tx == nil || newMsg == nil {
+			t.Fatalf("Segment: tx=%v newMsg=%v of %d receipts", tx, newMsg, len(rest.Receipts))
+		}
+		if tx.Size() == 0 {
+			t.Errorf("Segment: empty tx")
+		}
+		if newMsg.From != msg.From {
+			t.Errorf("Segment: newMsg from %q, want %q", newMsg.From, msg.From)
+		}
+		before, left := eventsOf(rest.Receipts), eventsOf(newMsg.Receipts)
+		if len(left) >= len(before) {
+			t.Fatalf("Segment: no event taken of %d", len(before))
+		}
+		taken := before[:len(before)-len(left)]
+		for i, e := range left {
+			if err := e.equal(before[len(taken)+i]); err != nil {
+				t.Fatalf("Segment: newMsg event %d: %v", i, err)
+			}
+		}
+		if n := receiptsOf(taken); opts.MaxReceipts > 0 && uint64(n) > opts.MaxReceipts {
+			t.Errorf("Segment: %d receipts taken, max %d", n, opts.MaxReceipts)
+		}
+		txs = append(txs, tx)
+		rest = newMsg
+	}
+
+	tx, newMsg, err := s.Segment(ctx, rest, opts)
+	if err != nil || tx != nil || newMsg == nil || len(newMsg.Receipts) > 0 {
+		t.Errorf("Segment: empty message: tx=%v newMsg=%v err=%v", tx, newMsg, err)
+	}
+	return txs
+}
+
+type event struct {
+	height, index uint64
+	*chain.Event
+}
+
+func eventsOf(receipts []*chain.Receipt) []*event {
+	var events []*event
+	for _, receipt := range receipts {
+		for _, e := range receipt.Events {
+			events = append(events, &event{receipt.Height, receipt.Index, e})
+		}
+	}
+	return events
+}
+
+// receiptsOf ...
+// returns the number of receipts the events belong to
+func receiptsOf(events []*event) int {
+	n := 0
+	for i, e := range events {
+		if i == 0 || e.height != events[i-1].height || e.index != events[i-1].index {
+			n++
+		}
+	}
+	return n
+}
+
+func (e *event) equal(o *event) error {
+	switch {
+	case e.height != o.height || e.index != o.index:
+		return fmt.Errorf("seq %d in receipt %d/%d, want %d/%d", e.Sequence, e.height, e.index, o.height, o.index)
+	case e.Sequence != o.Sequence:
+		return fmt.Errorf("seq %d, want %d", e.Sequence, o.Sequence)
+	case e.Next != o.Next || !bytes.Equal(e.Message, o.Message):
+		return fmt.Errorf("seq %d changed", e.Sequence)
+	}
+	return nil
+}
+
+// Subscribe ...
+// subscribes to "r" with "opts" until "n" events are received within
+// "timeout", and checks that they follow "opts.Seq" in order, in receipts
+// from "opts.Height" in order, and that the scanned heights reported by the
+// messages, with or without receipts, don't go back and cover their
+// receipts; then cancels the subscription and checks that its error
+// channel is closed; returns the messages received
+func Subscribe(t *testing.T, r chain.Receiver, opts chain.SubscribeOptions, n int, timeout time.Duration) []*chain.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	msgCh := make(chan *chain.Message)
+	errCh, err := r.Subscribe(ctx, msgCh, opts)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	var msgs []*chain.Message
+	seq, height := opts.Seq+1, opts.Height
+	var scanned uint64 // last height reported scanned
+	for received := 0; received < n; {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Subscribe: %d of %d events received in %v", received, n, timeout)
+		case err, ok := <-errCh:
+			if !ok {
+				t.Fatalf("Subscribe: terminated after %d of %d events", received, n)
+			}
+			t.Fatalf("Subscribe: %v", err)
+		case msg := <-msgCh:
+			msgs = append(msgs, msg)
+			if msg.Height > 0 && msg.Height < scanned {
+				t.Errorf("Subscribe: scanned height %d after %d", msg.Height, scanned)
+			}
+			for _, receipt := range msg.Receipts {
+				if receipt.Height < height {
+					t.Errorf("Subscribe: receipt at height %d, want %d or above", receipt.Height, height)
+				}
+				if scanned > 0 && receipt.Height <= scanned {
+					t.Errorf("Subscribe: receipt at height %d, scanned up to %d before", receipt.Height, scanned)
+				}
+				if msg.Height > 0 && receipt.Height > msg.Height {
+					t.Errorf("Subscribe: receipt at height %d, above the scanned height %d", receipt.Height, msg.Height)
+				}
+				if len(receipt.Events) == 0 {
+					t.Errorf("Subscribe: receipt at height %d without events", receipt.Height)
+				}
+				height = receipt.Height
+				for _, e := range receipt.Events {
+					if e.Sequence != seq {
+						t.Fatalf("Subscribe: seq %d at height %d, want %d", e.Sequence, receipt.Height, seq)
+					}
+					seq++
+					received++
+				}
+			}
+			if msg.Height > scanned {
+				scanned = msg.Height
+			}
+		}
+	}
+
+	cancel()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-errCh:
+			if !ok {
+				return msgs
+			}
+		case <-timer.C:
+			t.Fatalf("Subscribe: errCh not closed %v after cancel", timeout)
+		}
+	}
+}
+
+// Receipts ...
+// returns the receipts of "msgs", skipping the messages reporting the
+// scanned height only
+func Receipts(msgs []*chain.Message) []*chain.Receipt {
+	var receipts []*chain.Receipt
+	for _, msg := range msgs {
+		receipts = append(receipts, msg.Receipts...)
+	}
+	return receipts
+}
+
+// Close ...
+// closes "r" and checks that it can't subscribe afterwards
+func Close(t *testing.T, r chain.Receiver) {
+	t.Helper()
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	_, err := r.Subscribe(context.Background(), make(chan *chain.Message), chain.SubscribeOptions{})
+	if !errors.Is(err, chain.ErrClosed) {
+		t.Errorf("Subscribe after Close: %v, want %v", err, chain.ErrClosed)
+	}
+}
+
+// ErrorCase ...
+// is a failure of the fake node "Call" runs into, and the chain error the
+// adapter must return for it
+type ErrorCase struct {
+	Name string
+	Call func(ctx context.Context) error
+	Want error
+}
+
+// Errors ...
+// checks that each case returns its chain error
+func Errors(t *testing.T, cases []ErrorCase) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			err := c.Call(context.Background())
+			if !errors.Is(err, c.Want) {
+				t.Errorf("got %v, want %v", err, c.Want)
+			}
+		})
+	}
+}
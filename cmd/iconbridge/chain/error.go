@@ -7,9 +7,11 @@ import (
 
 var (
 	// Common errors
-	ErrInsufficientBalance   = errors.New("InsufficientBalance")
-	ErrGasLimitExceeded      = errors.New("GasLimitExceeded")
-	ErrBlockGasLimitExceeded = errors.New("BlockGasLimitExceeded")
+	ErrInsufficientBalance    = errors.New("InsufficientBalance")
+	ErrGasLimitExceeded       = errors.New("GasLimitExceeded")
+	ErrBlockGasLimitExceeded  = errors.New("BlockGasLimitExceeded")
+	ErrGasLimitCeilingReached = errors.New("GasLimitCeilingReached")
+	ErrClosed                 = errors.New("Closed")
 
 	// BMC errors
 	ErrBMCRevertLastOwner                 = errors.New("LastOwner")
@@ -0,0 +1,41 @@
+package chain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EVMSendError ...
+// maps the error of sending a relay tx to an evm chain to a chain error,
+// or returns it as is
+func EVMSendError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch msg := err.Error(); {
+	case msg == "insufficient funds for gas * price + value":
+		return ErrInsufficientBalance
+	case strings.Contains(msg, "exceeds block gas limit"):
+		// rejected by the tx pool, the tx wouldn't fit into a block
+		return ErrBlockGasLimitExceeded
+	}
+	return err
+}
+
+// EVMReceiptError ...
+// returns the chain error of a relay tx failed on an evm chain after using
+// "gasUsed" of its "gasLimit"; if it didn't run out of gas, "revertReason"
+// returns why it reverted
+func EVMReceiptError(gasUsed, gasLimit uint64, revertReason func() (string, error)) error {
+	if gasUsed >= gasLimit*63/64 {
+		return ErrGasLimitExceeded
+	}
+	reason, err := revertReason()
+	if err != nil {
+		return err
+	}
+	if err := RevertError(reason); err != nil {
+		return err
+	}
+	return fmt.Errorf("reverted: %q", reason)
+}
@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEVMSendError(t *testing.T) {
+	other := errors.New("nonce too low")
+	for _, c := range []struct {
+		err, want error
+	}{
+		{nil, nil},
+		{errors.New("insufficient funds for gas * price + value"), ErrInsufficientBalance},
+		{errors.New("exceeds block gas limit"), ErrBlockGasLimitExceeded},
+		{other, other},
+	} {
+		require.Equal(t, c.want, EVMSendError(c.err), "%v", c.err)
+	}
+}
+
+func TestEVMReceiptError(t *testing.T) {
+	revert := func(reason string, err error) func() (string, error) {
+		return func() (string, error) { return reason, err }
+	}
+	failed := errors.New("call failed")
+	for _, c := range []struct {
+		gasUsed uint64
+		revert  func() (string, error)
+		want    error
+	}{
+		{64000, revert("", failed), ErrGasLimitExceeded},
+		{63000, revert("", failed), ErrGasLimitExceeded},
+		{62000, revert("InvalidSeqNumber", nil), ErrBMCRevertInvalidSeqNumber},
+		{62000, revert("", failed), failed},
+	} {
+		require.Equal(t, c.want, EVMReceiptError(c.gasUsed, 64000, c.revert), "gasUsed %d", c.gasUsed)
+	}
+	require.Error(t, EVMReceiptError(100, 64000, revert("unknown", nil)), "unknown revert reason")
+}
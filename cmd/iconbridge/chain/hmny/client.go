@@ -135,6 +135,12 @@ func (cl *Client) syncVerifier(vr Verifier, height uint64) (err error) {
 	return nil
 }
 
+// Close ...
+// closes the rpc connection of the client
+func (cl *Client) Close() {
+	cl.rpc.Close()
+}
+
 func (cl *Client) GetChainID() (*big.Int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultReadTimeout)
 	defer cancel()
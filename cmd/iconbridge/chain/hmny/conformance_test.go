@@ -0,0 +1,289 @@
+//go:build hmny
+// +build hmny
+
+package hmny
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/chaintest"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/icon-project/icon-bridge/common/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// the conformance tests need the hmny tag, and the cgo bls libraries, to
+// build; see the package doc for where they run
+const (
+	conformanceHMNY = chain.BTPAddress("btp://0x6357d2e0.hmny/0xACBA72f72a56A15dBBFFFD638999Fd80A89A19Cf")
+	conformanceICON = chain.BTPAddress("btp://0x2.icon/cxfa5b7b8d71bf6355bf230ff22e1d4777a630c7ec")
+)
+
+// fakeNode ...
+// is the json-rpc api of the fake node, served as the eth, hmy and hmyv2
+// namespaces: it serves the blocks of the receipts it's made of, and empty
+// blocks around them, includes the txs it's sent with "receipt", and answers
+// calls with "call"; sending fails with "sendErr" if set
+type fakeNode struct {
+	receipts map[uint64]types.Receipts
+	byHash   map[common.Hash]uint64
+	last     uint64
+	sendErr  error
+	receipt  *ethtypes.Receipt
+	call     []byte
+	sent     *ethtypes.Transaction
+}
+
+// newFakeNode ...
+// returns a client of a node serving the events of "msg" as bmc logs, in
+// blocks at the heights of its receipts, and the node itself
+func newFakeNode(t *testing.T, msg *chain.Message) (*Client, *fakeNode) {
+	parsed, err := ethabi.JSON(strings.NewReader(BMCABI))
+	require.NoError(t, err)
+	event := parsed.Events["Message"]
+	bmc := common.HexToAddress(conformanceHMNY.ContractAddress())
+
+	n := &fakeNode{
+		receipts: make(map[uint64]types.Receipts),
+		byHash:   make(map[common.Hash]uint64),
+	}
+	for _, receipt := range msg.Receipts {
+		txr := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}
+		for _, ev := range receipt.Events {
+			data, err := event.Inputs.Pack(conformanceICON.String(), new(big.Int).SetUint64(ev.Sequence), ev.Message)
+			require.NoError(t, err)
+			txr.Logs = append(txr.Logs, &types.Log{
+				Address: bmc, Topics: []common.Hash{event.ID}, Data: data, BlockNumber: receipt.Height,
+			})
+		}
+		n.receipts[receipt.Height] = append(n.receipts[receipt.Height], txr)
+		n.last = receipt.Height
+	}
+	for height := range n.receipts {
+		h, _ := n.GetFullHeader(new(big.Int).SetUint64(height))
+		n.byHash[h.Hash()] = height
+	}
+
+	srv := rpc.NewServer()
+	for _, name := range []string{"eth", "hmy", "hmyv2"} {
+		require.NoError(t, srv.RegisterName(name, n))
+	}
+	rc := rpc.DialInProc(srv)
+	return &Client{log: log.New(), rpc: rc, eth: ethclient.NewClient(rc)}, n
+}
+
+func (n *fakeNode) GetFullHeader(height *big.Int) (*Header, error) {
+	h := &Header{Number: height}
+	binary.BigEndian.PutUint64(h.ParentHash[24:], height.Uint64()-1)
+	if receipts, ok := n.receipts[height.Uint64()]; ok {
+		h.GasUsed = 1
+		h.ReceiptsRoot = types.DeriveSha(receipts)
+	}
+	return h, nil
+}
+
+func (n *fakeNode) GetBlockReceipts(hash common.Hash) (types.Receipts, error) {
+	return n.receipts[n.byHash[hash]], nil
+}
+
+func (n *fakeNode) ChainId() (*hexutil.Big, error) {
+	return (*hexutil.Big)(big.NewInt(1666700000)), nil
+}
+
+func (n *fakeNode) BlockNumber() (hexutil.Uint64, error) {
+	return hexutil.Uint64(n.last + 3), nil
+}
+
+func (n *fakeNode) GetTransactionCount(addr common.Address, block string) (hexutil.Uint64, error) {
+	return 1, nil
+}
+
+func (n *fakeNode) SendRawTransaction(b hexutil.Bytes) (common.Hash, error) {
+	if n.sendErr != nil {
+		return common.Hash{}, n.sendErr
+	}
+	n.sent = new(ethtypes.Transaction)
+	if err := n.sent.UnmarshalBinary(b); err != nil {
+		return common.Hash{}, err
+	}
+	return n.sent.Hash(), nil
+}
+
+func (n *fakeNode) GetTransactionByHash(h common.Hash) (map[string]interface{}, error) {
+	b, err := n.sent.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	tx := make(map[string]interface{})
+	if err := json.Unmarshal(b, &tx); err != nil {
+		return nil, err
+	}
+	tx["blockNumber"] = hexutil.EncodeBig(n.receipt.BlockNumber)
+	return tx, nil
+}
+
+func (n *fakeNode) GetTransactionReceipt(h common.Hash) (*ethtypes.Receipt, error) {
+	r := *n.receipt
+	r.TxHash, r.Logs = h, []*ethtypes.Log{}
+	return &r, nil
+}
+
+func (n *fakeNode) Call(msg map[string]interface{}, block string) (hexutil.Bytes, error) {
+	return n.call, nil
+}
+
+// newConformanceSender ...
+// returns a sender relaying from icon to the fake node of "cl"
+func newConformanceSender(t *testing.T, cl *Client, sizeLimit uint64) *sender {
+	privKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	bmc, err := NewBMC(common.HexToAddress(conformanceHMNY.ContractAddress()), cl.eth)
+	require.NoError(t, err)
+	return &sender{
+		log:  log.New(),
+		w:    &wallet.EvmWallet{Skey: privKey, Pkey: &privKey.PublicKey},
+		src:  conformanceICON,
+		dst:  conformanceHMNY,
+		opts: senderOptions{BoostGasPrice: 1.0, TxDataSizeLimit: sizeLimit},
+		cls:  []*Client{cl},
+		bmcs: []*BMC{bmc},
+	}
+}
+
+// sendTx ...
+// sends a relay tx to a fake node, which rejects it with "sendErr", or
+// includes it with "txr" and answers calls with "data"; returns the error
+// of the tx
+func sendTx(ctx context.Context, t *testing.T, sendErr error, txr *ethtypes.Receipt, data []byte) error {
+	cl, n := newFakeNode(t, &chain.Message{})
+	defer cl.Close()
+	n.sendErr, n.receipt, n.call = sendErr, txr, data
+	tx, _, err := newConformanceSender(t, cl, 0).Segment(
+		ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+	if err != nil {
+		return err
+	}
+	if err := tx.Send(ctx); err != nil {
+		return err
+	}
+	_, err = tx.Receipt(ctx)
+	return err
+}
+
+// revertData ...
+// returns the result of a call reverted with "reason"
+func revertData(reason string) []byte {
+	data := make([]byte, 4+32+32, 4+32+32+len(reason))
+	binary.BigEndian.PutUint64(data[4+32+24:], uint64(len(reason)))
+	return append(data, reason...)
+}
+
+func TestConformance_Segment(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceICON, 100, 10, 20, 3, 100)
+	cl, _ := newFakeNode(t, &chain.Message{})
+	defer cl.Close()
+	for name, opts := range map[string]chain.SegmentOptions{
+		"default":     {},
+		"maxReceipts": {MaxReceipts: 2},
+		"maxSize":     {MaxSize: 512},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newConformanceSender(t, cl, 2048)
+			txs := chaintest.Segment(t, s, msg, opts)
+			require.Greater(t, len(txs), 1)
+		})
+	}
+}
+
+func TestConformance_Subscribe(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceHMNY, 102, 10, 5, 3, 10)
+	cl, _ := newFakeNode(t, msg)
+	bmc, err := NewBMC(common.HexToAddress(conformanceHMNY.ContractAddress()), cl.eth)
+	require.NoError(t, err)
+	r := &receiver{
+		log:  log.New(),
+		src:  conformanceHMNY,
+		dst:  conformanceICON,
+		opts: ReceiverOptions{SyncConcurrency: 4},
+		cls:  []*Client{cl},
+		bmcs: []*BMC{bmc},
+	}
+	msgs := chaintest.Subscribe(t, r, chain.SubscribeOptions{Seq: 11, Height: 100}, 13, time.Minute)
+	receipts := chaintest.Receipts(msgs)
+	require.NotEmpty(t, receipts)
+	require.Equal(t, uint64(12), receipts[0].Events[0].Sequence)
+	chaintest.Close(t, r)
+}
+
+func TestConformance_Errors(t *testing.T) {
+	chaintest.Errors(t, []chaintest.ErrorCase{
+		{
+			Name: "InsufficientBalance",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, errors.New("insufficient funds for gas * price + value"), nil, nil)
+			},
+			Want: chain.ErrInsufficientBalance,
+		},
+		{
+			Name: "BlockGasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, errors.New("exceeds block gas limit"), nil, nil)
+			},
+			Want: chain.ErrBlockGasLimitExceeded,
+		},
+		{
+			Name: "GasLimitCeilingReached",
+			Call: func(ctx context.Context) error {
+				cl, _ := newFakeNode(t, &chain.Message{})
+				defer cl.Close()
+				tx, _, err := newConformanceSender(t, cl, 0).Segment(
+					ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				for {
+					if _, err := tx.IncreaseGasLimit(); err != nil {
+						return err
+					}
+				}
+			},
+			Want: chain.ErrGasLimitCeilingReached,
+		},
+		{
+			Name: "GasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, nil, &ethtypes.Receipt{
+					GasUsed:     defaultGasLimit,
+					BlockNumber: big.NewInt(1),
+				}, nil)
+			},
+			Want: chain.ErrGasLimitExceeded,
+		},
+		{
+			Name: "Revert",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, nil, &ethtypes.Receipt{
+					GasUsed:     100000,
+					BlockNumber: big.NewInt(1),
+				}, revertData("InvalidSeqNumber"))
+			},
+			Want: chain.ErrBMCRevertInvalidSeqNumber,
+		},
+	})
+}
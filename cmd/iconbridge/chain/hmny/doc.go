@@ -0,0 +1,6 @@
+// Package hmny relays BTP messages from and to Harmony. It's built with the
+// hmny tag only, the harmony types it depends on need the cgo bls libraries
+// of build/base/Dockerfile, so a plain "go test ./..." doesn't run its tests:
+// the chaintest conformance ones run with "-tags hmny" in the iconbridge/build
+// image, see the "Run BMR hmny conformance tests" step of the build workflow.
+package hmny
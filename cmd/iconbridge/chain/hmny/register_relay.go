@@ -8,4 +8,8 @@ import "github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
 func init() {
 	relay.Senders["hmny"] = NewSender
 	relay.Receivers["hmny"] = NewReceiver
+	relay.LoopDefaults["hmny"] = relay.LoopConfig{
+		TickerInterval:        4, // 2s blocks
+		TxReceiptWaitInterval: 1,
+	}
 }
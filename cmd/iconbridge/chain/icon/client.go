@@ -46,6 +46,8 @@ import (
 const (
 	DefaultSendTransactionRetryInterval        = 3 * time.Second         //3sec
 	DefaultGetTransactionResultPollingInterval = 1500 * time.Millisecond //1.5sec
+
+	wsCloseTimeout = time.Second
 )
 
 type Wallet interface {
@@ -67,6 +69,7 @@ type IClient interface {
 	Monitor(ctx context.Context, reqUrl string, reqPtr, respPtr interface{}, cb types.WsReadCallback) error
 	CloseAllMonitor()
 	CloseMonitor(conn *websocket.Conn)
+	Close()
 
 	GetLastBlock() (*types.Block, error)
 	GetBlockHeaderByHeight(height int64) (*types.BlockHeader, error)
@@ -296,10 +299,20 @@ func (c *Client) Monitor(ctx context.Context, reqUrl string, reqPtr, respPtr int
 	if err != nil {
 		return ErrConnectFail
 	}
+	// close the connection to unblock its read once "ctx" is done
+	stop := make(chan struct{})
 	defer func() {
+		close(stop)
 		c.log.Debugf("Monitor finish %s", conn.LocalAddr().String())
 		c.wsClose(conn)
 	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.wsClose(conn)
+		case <-stop:
+		}
+	}()
 	if err = c.wsRequest(conn, reqPtr); err != nil {
 		return err
 	}
@@ -315,12 +328,34 @@ func (c *Client) CloseMonitor(conn *websocket.Conn) {
 }
 
 func (c *Client) CloseAllMonitor() {
+	c.mtx.Lock()
+	conns := make([]*websocket.Conn, 0, len(c.conns))
 	for _, conn := range c.conns {
+		conns = append(conns, conn)
+	}
+	c.mtx.Unlock()
+	for _, conn := range conns {
 		c.log.Debugf("CloseAllMonitor %s", conn.LocalAddr().String())
 		c.wsClose(conn)
 	}
 }
 
+// Close ...
+// closes the websocket connections of the monitors and the idle
+// connections of the json-rpc client
+func (c *Client) Close() {
+	c.CloseAllMonitor()
+	c.CloseIdleConnections()
+}
+
+func (c *Client) _hasWsConn(conn *websocket.Conn) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	_, ok := c.conns[conn.LocalAddr().String()]
+	return ok
+}
+
 func (c *Client) _addWsConn(conn *websocket.Conn) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
@@ -329,7 +364,7 @@ func (c *Client) _addWsConn(conn *websocket.Conn) {
 	c.conns[la] = conn
 }
 
-func (c *Client) _removeWsConn(conn *websocket.Conn) {
+func (c *Client) _removeWsConn(conn *websocket.Conn) bool {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 
@@ -338,6 +373,7 @@ func (c *Client) _removeWsConn(conn *websocket.Conn) {
 	if ok {
 		delete(c.conns, la)
 	}
+	return ok
 }
 
 type wsConnectError struct {
@@ -384,9 +420,14 @@ func (c *Client) wsRequest(conn *websocket.Conn, reqPtr interface{}) error {
 	return nil
 }
 
+// wsClose ...
+// closes "conn" once, it may be called concurrently with its reads and writes
 func (c *Client) wsClose(conn *websocket.Conn) {
-	c._removeWsConn(conn)
-	if err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
+	if !c._removeWsConn(conn) {
+		return
+	}
+	if err := conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(wsCloseTimeout)); err != nil {
 		c.log.Debugf("fail to WriteMessage CloseNormalClosure err:%+v", err)
 	}
 	if err := conn.Close(); err != nil {
@@ -414,7 +455,7 @@ func (c *Client) wsReadJSONLoop(ctx context.Context, conn *websocket.Conn, respP
 		default:
 			v := reflect.New(elem.Type())
 			ptr := v.Interface()
-			if _, ok := c.conns[conn.LocalAddr().String()]; !ok {
+			if !c._hasWsConn(conn) {
 				c.log.Debugf("wsReadJSONLoop c.conns[%s] is nil", conn.LocalAddr().String())
 				return errors.New("wsReadJSONLoop c.conns is nil")
 			}
@@ -0,0 +1,243 @@
+package icon
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/icon-project/goloop/common"
+	"github.com/icon-project/goloop/common/codec"
+	"github.com/icon-project/goloop/common/db"
+	"github.com/icon-project/goloop/common/trie/ompt"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/chaintest"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/icon/mocks"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/icon/types"
+	"github.com/icon-project/icon-bridge/common/jsonrpc"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/icon-project/icon-bridge/common/wallet"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	conformanceICON = chain.BTPAddress("btp://0x2.icon/cxfa5b7b8d71bf6355bf230ff22e1d4777a630c7ec")
+	conformanceBSC  = chain.BTPAddress("btp://0x61.bsc/0xACBA72f72a56A15dBBFFFD638999Fd80A89A19Cf")
+)
+
+// fakeBlock ...
+// is a block of the fake chain, with the proofs of its bmc events by
+// receipt index
+type fakeBlock struct {
+	notification *types.BlockNotification
+	header       *types.BlockHeader
+	proofs       map[int64][][][]byte
+}
+
+// newFakeChain ...
+// returns a client serving the events of "msg" as bmc event logs with their
+// proofs, in blocks at the heights of its receipts, and empty blocks around them
+func newFakeChain(t *testing.T, msg *chain.Message) *mocks.ClientMock {
+	bmc, err := types.Address(conformanceICON.ContractAddress()).Value()
+	require.NoError(t, err)
+	key := func(index int64) []byte {
+		k, err := codec.RLP.MarshalToBytes(index)
+		require.NoError(t, err)
+		return k
+	}
+	blocks := make(map[int64]*fakeBlock)
+	block := func(height int64) *fakeBlock {
+		if b, ok := blocks[height]; ok {
+			return b
+		}
+		hash := make([]byte, 32)
+		binary.BigEndian.PutUint64(hash[24:], uint64(height))
+		return &fakeBlock{
+			notification: &types.BlockNotification{
+				Hash:   types.NewHexBytes(hash),
+				Height: types.NewHexInt(height),
+			},
+			header: &types.BlockHeader{Height: height},
+			proofs: make(map[int64][][][]byte),
+		}
+	}
+
+	var last int64
+	byHeight := make(map[int64][]*chain.Receipt)
+	for _, receipt := range msg.Receipts {
+		last = int64(receipt.Height)
+		byHeight[last] = append(byHeight[last], receipt)
+	}
+	for height, receipts := range byHeight {
+		b := block(height)
+		mdb := db.NewMapDB()
+		receiptTrie := ompt.NewMPTForBytes(mdb, nil)
+		var indexes []types.HexInt
+		var events [][]types.HexInt
+		for _, receipt := range receipts {
+			index := int64(receipt.Index)
+			eventTrie := ompt.NewMPTForBytes(mdb, nil)
+			var seqs []types.HexInt
+			for j, e := range receipt.Events {
+				el, err := codec.RLP.MarshalToBytes(&types.EventLog{
+					Addr: bmc,
+					Indexed: [][]byte{
+						[]byte(EventSignature),
+						[]byte(conformanceBSC.String()),
+						common.NewHexInt(int64(e.Sequence)).Bytes(),
+					},
+					Data: [][]byte{e.Message},
+				})
+				require.NoError(t, err)
+				_, err = eventTrie.Set(key(int64(j)), el)
+				require.NoError(t, err)
+				seqs = append(seqs, types.NewHexInt(int64(j)))
+			}
+			txr, err := codec.RLP.MarshalToBytes(&TxResult{EventLogsHash: eventTrie.Hash()})
+			require.NoError(t, err)
+			_, err = receiptTrie.Set(key(index), txr)
+			require.NoError(t, err)
+			proofs := [][][]byte{nil}
+			for j := range receipt.Events {
+				proofs = append(proofs, eventTrie.GetProof(key(int64(j))))
+			}
+			b.proofs[index] = proofs
+			indexes = append(indexes, types.NewHexInt(index))
+			events = append(events, seqs)
+		}
+		for index, proofs := range b.proofs {
+			proofs[0] = receiptTrie.GetProof(key(index))
+		}
+		result, err := codec.RLP.MarshalToBytes(&BlockHeaderResult{ReceiptHash: receiptTrie.Hash()})
+		require.NoError(t, err)
+		b.header.Result = result
+		b.notification.Indexes = [][]types.HexInt{indexes}
+		b.notification.Events = [][][]types.HexInt{events}
+		blocks[height] = b
+	}
+
+	cl := new(mocks.ClientMock)
+	cl.On("MonitorBlock", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, p *types.BlockRequest, cb func(*websocket.Conn, *types.BlockNotification) error,
+			scb func(*websocket.Conn), errCb func(*websocket.Conn, error)) error {
+			height, err := p.Height.Value()
+			if err != nil {
+				return err
+			}
+			for ; height <= last+2; height++ {
+				if err := cb(nil, block(height).notification); err != nil {
+					return err
+				}
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	cl.On("GetBlockHeaderByHeight", mock.Anything).Return(
+		func(height int64) *types.BlockHeader {
+			return block(height).header
+		}, nil)
+	cl.On("GetProofForEvents", mock.Anything).Return(
+		func(p *types.ProofEventsParam) [][][]byte {
+			hash, _ := p.BlockHash.Value()
+			height := int64(binary.BigEndian.Uint64(hash[24:]))
+			index, _ := p.Index.Value()
+			return block(height).proofs[index]
+		}, nil)
+	cl.On("Close").Return()
+	return cl
+}
+
+// newConformanceSender ...
+// returns a sender relaying from bsc to the fake node "cl"
+func newConformanceSender(cl *Client, sizeLimit uint64) *sender {
+	return &sender{
+		log:  log.New(),
+		w:    wallet.New(),
+		src:  conformanceBSC,
+		dst:  conformanceICON,
+		opts: senderOptions{StepLimit: 1000, TxDataSizeLimit: sizeLimit},
+		cl:   cl,
+	}
+}
+
+// sendTx ...
+// sends a relay tx to a fake node, which includes it with "txr", and
+// returns the error of its receipt
+func sendTx(ctx context.Context, txr *types.TransactionResult) error {
+	srv, cl := newFakeNode(map[string]fakeMethod{
+		"icx_sendTransaction": func(json.RawMessage) (interface{}, *jsonrpc.Error) {
+			return "0x01", nil
+		},
+		"icx_getTransactionResult": result(txr),
+	})
+	defer srv.Close()
+	tx, _, err := newConformanceSender(cl, 0).Segment(
+		ctx, chaintest.NewMessage(conformanceBSC, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+	if err != nil {
+		return err
+	}
+	if err := tx.Send(ctx); err != nil {
+		return err
+	}
+	_, err = tx.Receipt(ctx)
+	return err
+}
+
+func TestConformance_Segment(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceBSC, 100, 10, 20, 3, 100)
+	for name, opts := range map[string]chain.SegmentOptions{
+		"default":     {},
+		"maxReceipts": {MaxReceipts: 2},
+		"maxSize":     {MaxSize: 512},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newConformanceSender(NewClient("http://localhost", log.New()), 2048)
+			txs := chaintest.Segment(t, s, msg, opts)
+			require.Greater(t, len(txs), 1)
+		})
+	}
+}
+
+func TestConformance_Subscribe(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceICON, 102, 10, 5, 3, 10)
+	rx, err := NewReceiver(conformanceICON, conformanceBSC, nil, json.RawMessage(`{"syncConcurrency":4}`), log.New())
+	require.NoError(t, err)
+	r := rx.(*Receiver)
+	r.Client = newFakeChain(t, msg)
+	msgs := chaintest.Subscribe(t, r, chain.SubscribeOptions{Seq: 11, Height: 100}, 13, time.Minute)
+	receipts := chaintest.Receipts(msgs)
+	require.NotEmpty(t, receipts)
+	require.Equal(t, uint64(12), receipts[0].Events[0].Sequence)
+	chaintest.Close(t, r)
+}
+
+func TestConformance_Errors(t *testing.T) {
+	chaintest.Errors(t, []chaintest.ErrorCase{
+		{
+			Name: "GasLimitCeilingReached",
+			Call: func(ctx context.Context) error {
+				tx, _, err := newConformanceSender(NewClient("http://localhost", log.New()), 0).Segment(
+					ctx, chaintest.NewMessage(conformanceBSC, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				for {
+					if _, err := tx.IncreaseGasLimit(); err != nil {
+						return err
+					}
+				}
+			},
+			Want: chain.ErrGasLimitCeilingReached,
+		},
+		{
+			Name: "GasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, failedResult(types.ResultStatusFailureCodeOutOfStep))
+			},
+			Want: chain.ErrGasLimitExceeded,
+		},
+	})
+}
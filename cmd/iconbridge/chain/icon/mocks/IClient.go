@@ -39,6 +39,11 @@ func (_m *ClientMock) CloseAllMonitor() {
 	_m.Called()
 }
 
+// Close provides a mock function with given fields:
+func (_m *ClientMock) Close() {
+	_m.Called()
+}
+
 // CloseMonitor provides a mock function with given fields: conn
 func (_m *ClientMock) CloseMonitor(conn *websocket.Conn) {
 	_m.Called(conn)
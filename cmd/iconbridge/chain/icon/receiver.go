@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/icon/types"
@@ -67,6 +68,7 @@ type Receiver struct {
 	opts      ReceiverOptions
 	blockReq  types.BlockRequest
 	logFilter eventLogRawFilter
+	subs      chain.Subscriptions
 }
 
 type verifierBlockResponse struct {
@@ -303,7 +305,7 @@ func handleVerifierBlockRequests(requestCh chan *verifierBlockRequest, client IC
 	return responseCh
 }
 
-func (r *Receiver) receiveLoop(ctx context.Context, startHeight, startSeq uint64, callback func(rs []*chain.Receipt) error) (err error) {
+func (r *Receiver) receiveLoop(ctx context.Context, startHeight, startSeq uint64, callback func(height uint64, rs []*chain.Receipt) error) (err error) {
 
 	blockReq, logFilter := r.blockReq, r.logFilter // copy
 
@@ -338,7 +340,12 @@ func (r *Receiver) receiveLoop(ctx context.Context, startHeight, startSeq uint64
 	next := int64(startHeight) // next block height to process
 
 	// subscribe to monitor block
+	var monitors sync.WaitGroup
 	ctxMonitorBlock, cancelMonitorBlock := context.WithCancel(ctx)
+	defer func() {
+		cancelMonitorBlock()
+		monitors.Wait()
+	}()
 	reconnect()
 
 loop:
@@ -356,23 +363,30 @@ loop:
 			ctxMonitorBlock, cancelMonitorBlock = context.WithCancel(ctx)
 
 			// start new monitor loop
+			monitors.Add(1)
 			go func(ctx context.Context, cancel context.CancelFunc) {
+				defer monitors.Done()
 				defer cancel()
 				blockReq.Height = types.NewHexInt(next)
 				err := r.Client.MonitorBlock(ctx, &blockReq,
 					func(conn *websocket.Conn, v *types.BlockNotification) error {
-						if !errors.Is(ctx.Err(), context.Canceled) {
-							btpBlockNotifCh <- v
+						select {
+						case btpBlockNotifCh <- v:
+						case <-ctx.Done():
 						}
 						return nil
 					},
 					func(conn *websocket.Conn) {},
 					func(c *websocket.Conn, err error) {})
 				if err != nil {
-					if errors.Is(err, context.Canceled) {
+					if ctx.Err() != nil {
+						return
+					}
+					select {
+					case <-time.After(time.Second * 5):
+					case <-ctx.Done():
 						return
 					}
-					time.Sleep(time.Second * 5)
 					reconnect()
 					r.log.WithFields(log.Fields{"error": err}).Error("reconnect: monitor block error")
 				}
@@ -630,7 +644,7 @@ func handleBTPBlockRequest(
 }
 
 func handleBTPBlockResponse(blockResponse *btpBlockResponse, vr IVerifier, next *int64,
-	reconnect func(), callback func(rs []*chain.Receipt) error,
+	reconnect func(), callback func(height uint64, rs []*chain.Receipt) error,
 	blockResponseCh chan *btpBlockResponse, logger log.Logger) error {
 
 	for ; blockResponse != nil; *next++ {
@@ -653,7 +667,7 @@ func handleBTPBlockResponse(blockResponse *btpBlockResponse, vr IVerifier, next
 				return errors.Wrapf(err, "receiveLoop: update verifier: %v", err)
 			}
 		}
-		if err := callback(blockResponse.Receipts); err != nil {
+		if err := callback(uint64(blockResponse.Height), blockResponse.Receipts); err != nil {
 			return errors.Wrapf(err, "receiveLoop: callback: %v", err)
 		}
 		if blockResponse = nil; len(blockResponseCh) > 0 {
@@ -680,9 +694,10 @@ func (r *Receiver) Subscribe(
 	}
 
 	_errCh := make(chan error)
-	go func() {
+	if err := r.subs.Go(ctx, func(ctx context.Context) {
 		defer close(_errCh)
-		err := r.receiveLoop(ctx, opts.Height, opts.Seq, func(receipts []*chain.Receipt) error {
+		var scan chain.ScanReport
+		err := r.receiveLoop(ctx, opts.Height, opts.Seq, func(height uint64, receipts []*chain.Receipt) error {
 			for _, receipt := range receipts {
 				events := receipt.Events[:0]
 				for _, event := range receipt.Events {
@@ -699,15 +714,34 @@ func (r *Receiver) Subscribe(
 				}
 				receipt.Events = events
 			}
-			if len(receipts) > 0 {
-				msgCh <- &chain.Message{Receipts: receipts}
+			if msg := scan.Message(height, receipts); msg != nil {
+				select {
+				case msgCh <- msg:
+				case <-ctx.Done():
+				}
 			}
 			return nil
 		})
 		if err != nil {
 			r.log.Errorf("receiveLoop terminated: %v", err)
-			_errCh <- err
+			select {
+			case _errCh <- err:
+			case <-ctx.Done():
+			}
 		}
-	}()
+	}); err != nil {
+		return nil, err
+	}
 	return _errCh, nil
 }
+
+// Close ...
+// terminates the subscriptions, closing their websocket connections,
+// and the connections of the client
+func (r *Receiver) Close() error {
+	r.subs.Close()
+	if r.Client != nil {
+		r.Client.Close()
+	}
+	return nil
+}
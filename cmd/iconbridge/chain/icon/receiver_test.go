@@ -416,7 +416,7 @@ func TestReceiver_receiveLoop_processBlockResult_countNext(t *testing.T) {
 		Height: 100,
 	}
 	var next int64 = 110
-	var callBackMock = func(rs []*chain.Receipt) error {
+	var callBackMock = func(height uint64, rs []*chain.Receipt) error {
 		return nil
 	}
 	blockResultCh := make(chan *btpBlockResponse, 3)
@@ -440,7 +440,7 @@ func TestReceiver_receiveLoop_processBlockResult_callbackError(t *testing.T) {
 	var callBackCallCounter = 0
 	var next int64 = 110
 
-	var callBack = func(rs []*chain.Receipt) error {
+	var callBack = func(height uint64, rs []*chain.Receipt) error {
 		callBackCallCounter++
 		return errors.New(errMessage)
 	}
@@ -5,4 +5,8 @@ import "github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
 func init() {
 	relay.Senders["icon"] = NewSender
 	relay.Receivers["icon"] = NewReceiver
+	relay.LoopDefaults["icon"] = relay.LoopConfig{
+		TickerInterval:        4, // 2s blocks
+		TxReceiptWaitInterval: 1,
+	}
 }
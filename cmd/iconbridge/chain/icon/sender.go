@@ -43,6 +43,8 @@ const (
 	defaultGetRelayResultInterval = time.Second
 	defaultRelayReSendInterval    = time.Second
 	defaultStepLimit              = 13610920010
+	stepLimitIncreaseScale        = 1.25 // raise step limit by 25% when a relay tx runs out of step
+	defaultMaxStepLimitScale      = 2    // default ceiling is twice the configured step limit
 )
 
 // NewSender ...
@@ -69,6 +71,7 @@ func NewSender(
 
 type senderOptions struct {
 	StepLimit        uint64         `json:"step_limit"`
+	MaxStepLimit     uint64         `json:"max_step_limit"`
 	TxDataSizeLimit  uint64         `json:"tx_data_size_limit"`
 	BalanceThreshold intconv.BigInt `json:"balance_threshold"`
 }
@@ -132,7 +135,7 @@ func (s *sender) Status(ctx context.Context) (*chain.BMCLinkStatus, error) {
 }
 
 func (s *sender) Segment(
-	ctx context.Context, msg *chain.Message,
+	ctx context.Context, msg *chain.Message, opts chain.SegmentOptions,
 ) (tx chain.RelayTx, newMsg *chain.Message, err error) {
 	if ctx.Err() != nil {
 		return nil, nil, ctx.Err()
@@ -153,12 +156,20 @@ func (s *sender) Segment(
 
 	var msgSize uint64
 
+	txSizeLimit := s.opts.TxDataSizeLimit
+	if opts.MaxSize > 0 && opts.MaxSize < txSizeLimit {
+		txSizeLimit = opts.MaxSize
+	}
+
 	newMsg = &chain.Message{
-		From:     msg.From,
-		Receipts: msg.Receipts,
+		From: msg.From,
 	}
 
 	for i, receipt := range msg.Receipts {
+		if opts.MaxReceipts > 0 && uint64(i) >= opts.MaxReceipts {
+			newMsg.Receipts = msg.Receipts[i:]
+			break
+		}
 		rlpEvents, err := codec.RLP.MarshalToBytes(receipt.Events)
 		if err != nil {
 			return nil, nil, err
@@ -172,7 +183,7 @@ func (s *sender) Segment(
 			return nil, nil, err
 		}
 		newMsgSize := msgSize + uint64(len(rlpReceipt))
-		if newMsgSize > s.opts.TxDataSizeLimit {
+		if newMsgSize > txSizeLimit {
 			newMsg.Receipts = msg.Receipts[i:]
 			break
 		}
@@ -198,6 +209,13 @@ func (s *sender) Balance(ctx context.Context) (balance, threshold *big.Int, err
 	return bal, &s.opts.BalanceThreshold.Int, err
 }
 
+// Close ...
+// closes the connections of the client
+func (s *sender) Close() error {
+	s.cl.Close()
+	return nil
+}
+
 func (s *sender) newRelayTx(ctx context.Context, prev string, message []byte) (*relayTx, error) {
 	txParam := &types.TransactionParam{
 		Version:     types.NewHexInt(types.JsonrpcApiVersion),
@@ -214,15 +232,24 @@ func (s *sender) newRelayTx(ctx context.Context, prev string, message []byte) (*
 			},
 		},
 	}
+	stepLimit := uint64(defaultStepLimit)
 	if s.opts.StepLimit > 0 {
+		stepLimit = s.opts.StepLimit
 		txParam.StepLimit = types.NewHexInt(int64(s.opts.StepLimit))
 	}
+	maxStepLimit := s.opts.MaxStepLimit
+	if maxStepLimit == 0 {
+		maxStepLimit = stepLimit * defaultMaxStepLimitScale
+	} else if maxStepLimit < stepLimit {
+		maxStepLimit = stepLimit
+	}
 	return &relayTx{
-		Prev:    prev,
-		Message: message,
-		txParam: txParam,
-		cl:      s.cl,
-		w:       s.w,
+		Prev:         prev,
+		Message:      message,
+		txParam:      txParam,
+		maxStepLimit: maxStepLimit,
+		cl:           s.cl,
+		w:            s.w,
 	}, nil
 }
 
@@ -230,10 +257,16 @@ type relayTx struct {
 	Prev    string `json:"_prev"`
 	Message []byte `json:"_msg"`
 
-	txParam     *types.TransactionParam
-	txHashParam *types.TransactionHashParam
-	cl          *Client
-	w           wallet.Wallet
+	txParam      *types.TransactionParam
+	txHashParam  *types.TransactionHashParam
+	maxStepLimit uint64
+	cl           *Client
+	w            wallet.Wallet
+	cost         *chain.TxCost
+}
+
+func (tx *relayTx) Size() int {
+	return len(tx.Message)
 }
 
 func (tx *relayTx) ID() interface{} {
@@ -243,6 +276,20 @@ func (tx *relayTx) ID() interface{} {
 	return nil
 }
 
+func (tx *relayTx) IncreaseGasLimit() (stepLimit uint64, err error) {
+	stepLimit = hexInt2Uint64(tx.txParam.StepLimit)
+	if stepLimit >= tx.maxStepLimit {
+		return stepLimit, chain.ErrGasLimitCeilingReached
+	}
+	stepLimit = uint64(float64(stepLimit) * stepLimitIncreaseScale)
+	if stepLimit > tx.maxStepLimit {
+		stepLimit = tx.maxStepLimit
+	}
+	tx.txParam.StepLimit = types.NewHexInt(int64(stepLimit))
+	tx.txHashParam = nil
+	return stepLimit, nil
+}
+
 func (tx *relayTx) Send(ctx context.Context) error {
 	tx.cl.log.WithFields(log.Fields{
 		"prev": tx.Prev}).Debug("handleRelayMessage: send tx")
@@ -301,6 +348,7 @@ func (tx *relayTx) Receipt(ctx context.Context) (blockHeight uint64, err error)
 	if tx.txHashParam == nil {
 		return 0, fmt.Errorf("no pending tx")
 	}
+	tx.cost = nil
 	for {
 		select {
 		case <-ctx.Done():
@@ -318,6 +366,13 @@ func (tx *relayTx) Receipt(ctx context.Context) (blockHeight uint64, err error)
 			}
 			return 0, mapErrorWithTransactionResult(txr, err)
 		}
+		// failed txs are paid for too
+		stepUsed, _ := txr.StepUsed.Value()
+		stepPrice, _ := txr.StepPrice.BigInt()
+		tx.cost = chain.NewTxCost(uint64(stepUsed), stepPrice)
+		if txr.Status != types.ResultStatusSuccess {
+			return 0, mapErrorWithTransactionResult(txr, nil)
+		}
 		tx.cl.log.WithFields(log.Fields{
 			"txh": tx.txHashParam.Hash}).Debug("handleRelayMessage: success")
 		height, _ := txr.BlockHeight.Value()
@@ -325,6 +380,10 @@ func (tx *relayTx) Receipt(ctx context.Context) (blockHeight uint64, err error)
 	}
 }
 
+func (tx *relayTx) Cost() *chain.TxCost {
+	return tx.cost
+}
+
 func mapError(err error) error {
 	if err != nil {
 		switch re := err.(type) {
@@ -364,8 +423,13 @@ func mapError(err error) error {
 func mapErrorWithTransactionResult(txr *types.TransactionResult, err error) error {
 	err = mapError(err)
 	if err == nil && txr != nil && txr.Status != types.ResultStatusSuccess {
+		if txr.Failure == nil {
+			return fmt.Errorf("failure with status:%s", txr.Status)
+		}
 		fc, _ := txr.Failure.CodeValue.Value()
-		if fc < types.ResultStatusFailureCodeRevert || fc > types.ResultStatusFailureCodeEnd {
+		if fc == types.ResultStatusFailureCodeOutOfStep {
+			err = chain.ErrGasLimitExceeded
+		} else if fc < types.ResultStatusFailureCodeRevert || fc > types.ResultStatusFailureCodeEnd {
 			err = fmt.Errorf("failure with code:%s, message:%s",
 				txr.Failure.CodeValue, txr.Failure.MessageValue)
 		} else {
@@ -0,0 +1,125 @@
+package icon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/icon/types"
+	"github.com/icon-project/icon-bridge/common/jsonrpc"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMethod ...
+// answers a jsonrpc request with a result or an error
+type fakeMethod func(params json.RawMessage) (interface{}, *jsonrpc.Error)
+
+// newFakeNode ...
+// returns a jsonrpc server answering "methods", and a client connected to it
+func newFakeNode(methods map[string]fakeMethod) (*httptest.Server, *Client) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &jsonrpc.Request{}
+		resp := &jsonrpc.Response{Version: jsonrpc.Version}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.ErrorCodeJsonParse, Message: err.Error()}
+		} else if method, ok := methods[req.Method]; ok {
+			resp.ID = req.ID
+			resp.Result, resp.Error = method(req.Params)
+		} else {
+			resp.ID = req.ID
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.ErrorCodeMethodNotFound, Message: req.Method}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	return srv, NewClient(srv.URL, log.New())
+}
+
+// result ...
+// returns a method answering "txr"
+func result(txr *types.TransactionResult) fakeMethod {
+	return func(json.RawMessage) (interface{}, *jsonrpc.Error) {
+		return txr, nil
+	}
+}
+
+// failedResult ...
+// returns the result of a tx failed with "code"
+func failedResult(code int64) *types.TransactionResult {
+	txr := &types.TransactionResult{
+		Status:      "0x0",
+		StepUsed:    types.NewHexInt(1000),
+		StepPrice:   types.NewHexInt(12500000000),
+		BlockHeight: types.NewHexInt(100),
+	}
+	txr.Failure = &struct {
+		CodeValue    types.HexInt `json:"code"`
+		MessageValue string       `json:"message"`
+	}{types.NewHexInt(code), "failure"}
+	return txr
+}
+
+func TestSender_IncreaseGasLimit(t *testing.T) {
+	tx := &relayTx{
+		txParam:      &types.TransactionParam{StepLimit: types.NewHexInt(1000)},
+		txHashParam:  &types.TransactionHashParam{Hash: "0x01"},
+		maxStepLimit: 2000,
+	}
+	limits := []uint64{1250, 1562, 1952, 2000}
+	for _, want := range limits {
+		got, err := tx.IncreaseGasLimit()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Nil(t, tx.txHashParam, "tx must be signed again")
+	}
+	got, err := tx.IncreaseGasLimit()
+	require.ErrorIs(t, err, chain.ErrGasLimitCeilingReached)
+	require.Equal(t, uint64(2000), got)
+}
+
+// receipt ...
+// returns the receipt of a tx included with "txr", and its cost
+func receipt(txr *types.TransactionResult) (uint64, *chain.TxCost, error) {
+	srv, cl := newFakeNode(map[string]fakeMethod{
+		"icx_getTransactionResult": result(txr),
+	})
+	defer srv.Close()
+	tx := &relayTx{
+		txHashParam: &types.TransactionHashParam{Hash: "0x01"},
+		cl:          cl,
+	}
+	height, err := tx.Receipt(context.Background())
+	return height, tx.Cost(), err
+}
+
+func TestSender_Receipt(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		txr := failedResult(0)
+		txr.Status, txr.Failure = types.ResultStatusSuccess, nil
+		height, cost, err := receipt(txr)
+		require.NoError(t, err)
+		require.Equal(t, uint64(100), height)
+		require.NotNil(t, cost)
+	})
+	t.Run("outOfStep", func(t *testing.T) {
+		_, cost, err := receipt(failedResult(types.ResultStatusFailureCodeOutOfStep))
+		require.ErrorIs(t, err, chain.ErrGasLimitExceeded)
+		require.NotNil(t, cost, "failed txs are paid for")
+		require.Equal(t, uint64(1000), cost.GasUsed)
+	})
+	t.Run("revert", func(t *testing.T) {
+		_, _, err := receipt(failedResult(types.ResultStatusFailureCodeRevert + int64(BMCRevertInvalidSN)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "BMCRevertInvalidSN")
+	})
+	t.Run("noFailure", func(t *testing.T) {
+		txr := failedResult(0)
+		txr.Failure = nil
+		_, _, err := receipt(txr)
+		require.Error(t, err)
+	})
+}
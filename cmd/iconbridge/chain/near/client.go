@@ -64,10 +64,11 @@ type IClient interface {
 	GetTransactionResult(types.CryptoHash, types.AccountId) (types.TransactionResult, error)
 	GetBlockProducers(types.CryptoHash) (types.BlockProducers, error)
 	Logger() log.Logger
-	MonitorBlocks(height uint64, concurrency int, callback func(observable rxgo.Observable) error) error
-	MonitorBlockHeight(offset int64) rxgo.Observable
+	MonitorBlocks(ctx context.Context, height uint64, concurrency int, callback func(observable rxgo.Observable) error) error
+	MonitorBlockHeight(ctx context.Context, offset int64) rxgo.Observable
 	SendTransaction(payload string) (*types.CryptoHash, error)
 	SerializeBlocks(interface{}) int
+	Close()
 }
 
 func (c *Client) Api() IApi {
@@ -316,7 +317,9 @@ func (c *Client) Logger() log.Logger {
 	return c.logger
 }
 
-func (c *Client) MonitorBlockHeight(offset int64) rxgo.Observable {
+// MonitorBlockHeight ...
+// emits the block heights from "offset" as they're produced, until "ctx" is done
+func (c *Client) MonitorBlockHeight(ctx context.Context, offset int64) rxgo.Observable {
 	channel := make(chan rxgo.Item)
 	go func(offset int64) {
 		defer close(channel)
@@ -324,12 +327,18 @@ func (c *Client) MonitorBlockHeight(offset int64) rxgo.Observable {
 		lastestBlockHeight, err := c.GetLatestBlockHeight()
 		if err != nil {
 			// TODO: Handle Error
-			channel <- rxgo.Error(err)
+			select {
+			case channel <- rxgo.Error(err):
+			case <-ctx.Done():
+			}
 			return
 		}
 
 		if lastestBlockHeight < 1 {
-			channel <- rxgo.Error(errors.New("invalid block height"))
+			select {
+			case channel <- rxgo.Error(errors.New("invalid block height")):
+			case <-ctx.Done():
+			}
 			return
 		}
 
@@ -344,12 +353,20 @@ func (c *Client) MonitorBlockHeight(offset int64) rxgo.Observable {
 
 				rangeHeight = lastestBlockHeight - offset
 				if rangeHeight < 3 {
-					time.Sleep(time.Second * 2)
-					continue
+					select {
+					case <-time.After(time.Second * 2):
+						continue
+					case <-ctx.Done():
+						return
+					}
 				}
 			}
 
-			channel <- rxgo.Of(offset)
+			select {
+			case channel <- rxgo.Of(offset):
+			case <-ctx.Done():
+				return
+			}
 			offset += 1
 		}
 	}(offset)
@@ -357,8 +374,8 @@ func (c *Client) MonitorBlockHeight(offset int64) rxgo.Observable {
 	return rxgo.FromChannel(channel, rxgo.WithCPUPool())
 }
 
-func (c *Client) MonitorBlocks(height uint64, concurrency int, callback func(observable rxgo.Observable) error) error {
-	return callback(c.MonitorBlockHeight(int64(height)).Map(func(_ context.Context, offset interface{}) (interface{}, error) {
+func (c *Client) MonitorBlocks(ctx context.Context, height uint64, concurrency int, callback func(observable rxgo.Observable) error) error {
+	return callback(c.MonitorBlockHeight(ctx, int64(height)).Map(func(_ context.Context, offset interface{}) (interface{}, error) {
 		if offset, Ok := (offset).(int64); Ok {
 			block, err := c.GetBlockByHeight(offset)
 			bn := types.NewBlockNotification(offset)
@@ -374,7 +391,15 @@ func (c *Client) MonitorBlocks(height uint64, concurrency int, callback func(obs
 		}
 
 		return nil, fmt.Errorf("error casting offset to int64")
-	}, rxgo.WithPool(concurrency), rxgo.WithErrorStrategy(rxgo.ContinueOnError)))
+	}, rxgo.WithPool(concurrency), rxgo.WithErrorStrategy(rxgo.ContinueOnError), rxgo.WithContext(ctx)))
+}
+
+// Close ...
+// closes the idle connections of the json-rpc client
+func (c *Client) Close() {
+	if cl, ok := c.api.(interface{ CloseIdleConnections() }); ok {
+		cl.CloseIdleConnections()
+	}
 }
 
 func NewClient(endpoint string, logger log.Logger) (IClient, error) {
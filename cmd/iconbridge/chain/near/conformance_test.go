@@ -0,0 +1,193 @@
+package near
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/chaintest"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/near/types"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/icon-project/icon-bridge/common/wallet"
+	"github.com/near/borsh-go"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	conformanceNEAR = chain.BTPAddress("btp://0x1.near/dev-20211206025826-24100687319598")
+	conformanceICON = chain.BTPAddress("btp://0x7.icon/cx1ad6fcc465d1b8644ca375f9e10babeea4c38315")
+)
+
+// fakeApi ...
+// is the json-rpc api of a fake node: it serves the bmc messages of the
+// blocks with receipts as state changes, one per event, and empty blocks
+// around them
+type fakeApi struct {
+	changes map[int64][]types.Change
+	last    int64
+}
+
+// newFakeClient ...
+// returns a client of a node serving the events of "msg", in blocks at the
+// heights of its receipts
+func newFakeClient(t *testing.T, msg *chain.Message) *Client {
+	a := &fakeApi{changes: make(map[int64][]types.Change)}
+	for _, receipt := range msg.Receipts {
+		height := int64(receipt.Height)
+		for _, e := range receipt.Events {
+			event, err := json.Marshal(struct {
+				Next     chain.BTPAddress
+				Sequence string
+				Message  []byte
+			}{conformanceICON, strconv.FormatUint(e.Sequence, 10), e.Message})
+			require.NoError(t, err)
+			value, err := borsh.Serialize(string(event))
+			require.NoError(t, err)
+			a.changes[height] = append(a.changes[height], types.Change{
+				Data: types.ChangeData{ValueBase64: base64.URLEncoding.EncodeToString(value)},
+			})
+		}
+		a.last = height
+	}
+	return &Client{api: a, logger: log.New()}
+}
+
+// blockId ...
+// returns the height, or the hash, of the block "param" asks for
+func blockId(param interface{}) (height int64, hash string) {
+	b, _ := json.Marshal(param)
+	var p struct {
+		BlockId json.RawMessage `json:"block_id"`
+	}
+	json.Unmarshal(b, &p)
+	if json.Unmarshal(p.BlockId, &hash) == nil {
+		h := types.NewCryptoHash(hash)
+		return int64(binary.BigEndian.Uint64(h[24:])), hash
+	}
+	json.Unmarshal(p.BlockId, &height)
+	return height, ""
+}
+
+func (a *fakeApi) Block(param interface{}) (types.Block, error) {
+	height, _ := blockId(param)
+	b := types.Block{}
+	b.Header.Height = height
+	b.Header.Hash[0] = 1
+	binary.BigEndian.PutUint64(b.Header.Hash[24:], uint64(height))
+	return b, nil
+}
+
+func (a *fakeApi) Changes(param interface{}) (types.ContractStateChange, error) {
+	height, _ := blockId(param)
+	return types.ContractStateChange{Changes: a.changes[height]}, nil
+}
+
+func (a *fakeApi) Status(param interface{}) (types.ChainStatus, error) {
+	return types.ChainStatus{SyncInfo: types.ChainSyncInfo{LatestBlockHeight: a.last + 5}}, nil
+}
+
+func (a *fakeApi) BlockProducers(param interface{}) (types.BlockProducers, error) {
+	return types.BlockProducers{}, nil
+}
+
+func (a *fakeApi) BroadcastTxCommit(param interface{}) (types.TransactionResult, error) {
+	return types.TransactionResult{}, nil
+}
+
+func (a *fakeApi) BroadcastTxAsync(param interface{}) (types.CryptoHash, error) {
+	return types.CryptoHash{1}, nil
+}
+
+func (a *fakeApi) CallFunction(param interface{}) (types.CallFunctionResponse, error) {
+	return types.CallFunctionResponse{}, nil
+}
+
+func (a *fakeApi) Chunk(param interface{}) (types.ChunkHeader, error) {
+	return types.ChunkHeader{}, nil
+}
+
+func (a *fakeApi) LightClientProof(param interface{}) (types.ReceiptProof, error) {
+	return types.ReceiptProof{}, nil
+}
+
+func (a *fakeApi) Transaction(param interface{}) (types.TransactionResult, error) {
+	return types.TransactionResult{}, nil
+}
+
+func (a *fakeApi) ViewAccessKey(param interface{}) (types.AccessKeyResponse, error) {
+	return types.AccessKeyResponse{Nonce: 1}, nil
+}
+
+func (a *fakeApi) ViewAccount(param interface{}) (types.Account, error) {
+	return types.Account{}, nil
+}
+
+// newConformanceSender ...
+// returns a sender relaying from icon to the fake node of "client"
+func newConformanceSender(t *testing.T, client *Client) *Sender {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	w, err := wallet.NewNearwalletFromPrivateKey(&privateKey)
+	require.NoError(t, err)
+	s, err := NewSender(SenderConfig{source: conformanceICON, destination: conformanceNEAR, wallet: w}, log.New(), client)
+	require.NoError(t, err)
+	return s
+}
+
+func TestConformance_Segment(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceICON, 100, 10, 20, 3, 2000)
+	for name, opts := range map[string]chain.SegmentOptions{
+		"default":     {},
+		"maxReceipts": {MaxReceipts: 2},
+		"maxSize":     {MaxSize: 16384},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newConformanceSender(t, newFakeClient(t, &chain.Message{}))
+			txs := chaintest.Segment(t, s, msg, opts)
+			require.Greater(t, len(txs), 1)
+		})
+	}
+}
+
+func TestConformance_Subscribe(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceNEAR, 102, 10, 5, 1, 10)
+	r, err := NewReceiver(ReceiverConfig{
+		source:      conformanceNEAR,
+		destination: conformanceICON,
+		options:     types.ReceiverOptions{SyncConcurrency: 4},
+	}, log.New(), newFakeClient(t, msg))
+	require.NoError(t, err)
+	msgs := chaintest.Subscribe(t, r, chain.SubscribeOptions{Seq: 11, Height: 100}, 3, time.Minute)
+	receipts := chaintest.Receipts(msgs)
+	require.NotEmpty(t, receipts)
+	require.Equal(t, uint64(12), receipts[0].Events[0].Sequence)
+	chaintest.Close(t, r)
+}
+
+// TestConformance_Errors ...
+// the near adapter returns the errors of the node as they are, so the only
+// chain error it can run into is the gas ceiling: relay txs attach the
+// maximum prepaid gas already
+func TestConformance_Errors(t *testing.T) {
+	chaintest.Errors(t, []chaintest.ErrorCase{
+		{
+			Name: "GasLimitCeilingReached",
+			Call: func(ctx context.Context) error {
+				tx, _, err := newConformanceSender(t, newFakeClient(t, &chain.Message{})).Segment(
+					ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				_, err = tx.IncreaseGasLimit()
+				return err
+			},
+			Want: chain.ErrGasLimitCeilingReached,
+		},
+	})
+}
@@ -21,13 +21,15 @@ type ReceiverConfig struct {
 }
 
 type Receiver struct {
-	clients      []IClient
-	source       chain.BTPAddress
-	destination  chain.BTPAddress
-	logger       log.Logger
-	verifier     *Verifier
-	options      types.ReceiverOptions
-	closeMonitor bool
+	clients     []IClient
+	source      chain.BTPAddress
+	destination chain.BTPAddress
+	logger      log.Logger
+	verifier    *Verifier
+	options     types.ReceiverOptions
+	subs        chain.Subscriptions
+	stopCh      chan struct{} // closed to stop receiving blocks
+	stopOnce    sync.Once
 }
 
 func receiverFactory(source, destination chain.BTPAddress, urls []string, opt json.RawMessage, logger log.Logger) (chain.Receiver, error) {
@@ -52,23 +54,23 @@ func NewReceiver(config ReceiverConfig, logger log.Logger, clients ...IClient) (
 	}
 
 	r := &Receiver{
-		clients:      clients,
-		logger:       logger,
-		source:       config.source,
-		destination:  config.destination,
-		options:      config.options,
-		closeMonitor: false,
+		clients:     clients,
+		logger:      logger,
+		source:      config.source,
+		destination: config.destination,
+		options:     config.options,
+		stopCh:      make(chan struct{}),
 	}
 
 	return r, nil
 }
 
-func (r *Receiver) MapReceipts(height types.Height, source string, observable rxgo.Observable) rxgo.Observable {
+func (r *Receiver) MapReceipts(ctx context.Context, height types.Height, source string, observable rxgo.Observable) rxgo.Observable {
 	
 	return observable.Map(
 		r.client().FetchReceipts,
 		rxgo.WithPool(r.options.SyncConcurrency),
-		rxgo.WithContext(context.WithValue(context.Background(), Source{}, source)),
+		rxgo.WithContext(context.WithValue(ctx, Source{}, source)),
 	).Serialize(
 		height.Int(),
 		r.client().SerializeBlocks,
@@ -77,10 +79,14 @@ func (r *Receiver) MapReceipts(height types.Height, source string, observable rx
 	)
 }
 
-func (r *Receiver) ReceiveBlocks(height uint64, source string, processBlockNotification func(blockNotification *types.BlockNotification)) error {
+// ReceiveBlocks ...
+// processes the blocks from "height" until "ctx" is done or the receiver is stopped
+func (r *Receiver) ReceiveBlocks(ctx context.Context, height uint64, source string, processBlockNotification func(blockNotification *types.BlockNotification)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	return r.client().MonitorBlocks(height, r.options.SyncConcurrency, func(observable rxgo.Observable) error {
-		result := r.MapReceipts(types.Height(height), source, observable).Scan(
+	return r.client().MonitorBlocks(ctx, height, r.options.SyncConcurrency, func(observable rxgo.Observable) error {
+		result := r.MapReceipts(ctx, types.Height(height), source, observable).Scan(
 			func(_ context.Context, acc interface{}, bn interface{}) (interface{}, error) {
 				blockNotification, _ := bn.(*types.BlockNotification)
 
@@ -95,7 +101,12 @@ func (r *Receiver) ReceiveBlocks(height uint64, source string, processBlockNotif
 				return blockNotification, nil
 			},
 		).TakeUntil(func(_ interface{}) bool {
-			return r.closeMonitor
+			select {
+			case <-r.stopCh:
+				return true
+			default:
+				return false
+			}
 		}).Observe()
 
 		for item := range result {
@@ -135,7 +146,7 @@ func (r *Receiver) Subscribe(ctx context.Context, msgCh chan<- *chain.Message, o
 		return _errCh, err
 	}
 
-	go func() {
+	if err := r.subs.Go(ctx, func(ctx context.Context) {
 		defer close(_errCh)
 
 		if r.verifier != nil {
@@ -143,15 +154,20 @@ func (r *Receiver) Subscribe(ctx context.Context, msgCh chan<- *chain.Message, o
 			wg.Add(1)
 
 			r.logger.WithFields(log.Fields{"start": r.options.Verifier.BlockHeight, "target": opts.Height - 1}).Debug("syncing verifier head")
-			if err := r.verifier.SyncHeader(wg, opts.Height-1); err != nil {
-				_errCh <- err
+			if err := r.verifier.SyncHeader(ctx, wg, opts.Height-1); err != nil {
+				select {
+				case _errCh <- err:
+				case <-ctx.Done():
+				}
+				return
 			}
 
 			wg.Wait()
 			r.logger.Debug("syncing complete")
 		}
 
-		if err := r.ReceiveBlocks(opts.Height, r.source.ContractAddress(), func(blockNotification *types.BlockNotification) {
+		var scan chain.ScanReport
+		if err := r.ReceiveBlocks(ctx, opts.Height, r.source.ContractAddress(), func(blockNotification *types.BlockNotification) {
 			receipts := make([]*chain.Receipt, 0)
 
 			for _, receipt := range blockNotification.Receipts() {
@@ -167,7 +183,10 @@ func (r *Receiver) Subscribe(ctx context.Context, msgCh chan<- *chain.Message, o
 							"seq": log.Fields{"got": event.Sequence, "expected": opts.Seq},
 						}).Error("invalid event seq")
 
-						_errCh <- fmt.Errorf("invalid event seq")
+						select {
+						case _errCh <- fmt.Errorf("invalid event seq"):
+						case <-ctx.Done():
+						}
 
 						return
 					}
@@ -180,16 +199,22 @@ func (r *Receiver) Subscribe(ctx context.Context, msgCh chan<- *chain.Message, o
 				}
 			}
 
-			if len(receipts) > 0 {
-				msgCh <- &chain.Message{
-					From:     r.source,
-					Receipts: receipts,
+			if msg := scan.Message(uint64(blockNotification.Block().Height()), receipts); msg != nil {
+				msg.From = r.source
+				select {
+				case msgCh <- msg:
+				case <-ctx.Done():
 				}
 			}
 		}); err != nil {
-			_errCh <- err
+			select {
+			case _errCh <- err:
+			case <-ctx.Done():
+			}
 		}
-	}()
+	}); err != nil {
+		return nil, err
+	}
 
 	return _errCh, nil
 }
@@ -199,5 +224,16 @@ func (r *Receiver) client() IClient {
 }
 
 func (r *Receiver) StopReceivingBlocks() {
-	r.closeMonitor = true
+	r.stopOnce.Do(func() { close(r.stopCh) })
+}
+
+// Close ...
+// stops receiving blocks, terminates the subscriptions and closes the clients
+func (r *Receiver) Close() error {
+	r.StopReceivingBlocks()
+	r.subs.Close()
+	for _, client := range r.clients {
+		client.Close()
+	}
+	return nil
 }
@@ -34,12 +34,12 @@ func TestNearReceiver(t *testing.T) {
 					require.Nil(f, err)
 
 					if testData.Expected.Success != nil {
-						err = receiver.ReceiveBlocks(input.Offset, input.Source.ContractAddress(), func(blockNotification *types.BlockNotification) {
+						err = receiver.ReceiveBlocks(context.Background(), input.Offset, input.Source.ContractAddress(), func(blockNotification *types.BlockNotification) {
 							assert.True(f, testData.Expected.Success.(func(*types.BlockNotification, func()) bool)(blockNotification, receiver.StopReceivingBlocks))
 						})
 						assert.Nil(f, err)
 					} else {
-						err = receiver.ReceiveBlocks(input.Offset, input.Source.ContractAddress(), func(blockNotification *types.BlockNotification) {
+						err = receiver.ReceiveBlocks(context.Background(), input.Offset, input.Source.ContractAddress(), func(blockNotification *types.BlockNotification) {
 							if err != nil {
 								assert.True(f, testData.Expected.Fail.(func(error) bool)(err))
 							}
@@ -5,4 +5,8 @@ import "github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
 func init() {
 	relay.Senders["near"] = senderFactory
 	relay.Receivers["near"] = receiverFactory
+	relay.LoopDefaults["near"] = relay.LoopConfig{
+		TickerInterval:        3, // ~1s blocks
+		TxReceiptWaitInterval: 1,
+	}
 }
@@ -77,7 +77,7 @@ func (s *Sender) client() IClient {
 	return s.clients[rand.Intn(len(s.clients))]
 }
 
-func (s *Sender) Segment(ctx context.Context, msg *chain.Message) (tx chain.RelayTx, newMsg *chain.Message, err error) {
+func (s *Sender) Segment(ctx context.Context, msg *chain.Message, opts chain.SegmentOptions) (tx chain.RelayTx, newMsg *chain.Message, err error) {
 	if ctx.Err() != nil {
 		return nil, nil, ctx.Err()
 	}
@@ -92,11 +92,19 @@ func (s *Sender) Segment(ctx context.Context, msg *chain.Message) (tx chain.Rela
 
 	var msgSize uint64
 
+	txSizeLimit := uint64(txMaxDataSize)
+	if opts.MaxSize > 0 && opts.MaxSize < txSizeLimit {
+		txSizeLimit = opts.MaxSize
+	}
+
 	newMsg = &chain.Message{
-		From:     msg.From,
-		Receipts: msg.Receipts,
+		From: msg.From,
 	}
 	for i, receipt := range msg.Receipts {
+		if opts.MaxReceipts > 0 && uint64(i) >= opts.MaxReceipts {
+			newMsg.Receipts = msg.Receipts[i:]
+			break
+		}
 		rlpEvents, err := codec.RLP.MarshalToBytes(receipt.Events)
 		if err != nil {
 			return nil, nil, err
@@ -110,7 +118,7 @@ func (s *Sender) Segment(ctx context.Context, msg *chain.Message) (tx chain.Rela
 			return nil, nil, err
 		}
 		newMsgSize := msgSize + uint64(len(rlpReceipt))
-		if newMsgSize > txMaxDataSize {
+		if newMsgSize > txSizeLimit {
 			newMsg.Receipts = msg.Receipts[i:]
 			break
 		}
@@ -157,7 +165,9 @@ func (s *Sender) newRelayTransaction(ctx context.Context, prev string, message [
 			},
 		}
 
-		return NewRelayTransaction(ctx, nearWallet, s.destination.ContractAddress(), s.client(), actions), nil
+		tx := NewRelayTransaction(ctx, nearWallet, s.destination.ContractAddress(), s.client(), actions)
+		tx.size = len(message)
+		return tx, nil
 	}
 
 	return nil, fmt.Errorf("failed to cast wallet")
@@ -168,6 +178,8 @@ type RelayTransaction struct {
 	client      IClient
 	wallet      *wallet.NearWallet
 	context     context.Context
+	size        int
+	cost        *chain.TxCost
 }
 
 func NewRelayTransaction(context context.Context, wallet *wallet.NearWallet, destination string, client IClient, actions []types.Action) *RelayTransaction {
@@ -186,6 +198,10 @@ func NewRelayTransaction(context context.Context, wallet *wallet.NearWallet, des
 	}
 }
 
+func (relayTx *RelayTransaction) Size() int {
+	return relayTx.size
+}
+
 func (relayTx *RelayTransaction) ID() interface{} {
 	if relayTx.Transaction.Txid != [32]byte{} {
 		return relayTx.Transaction.Txid
@@ -193,11 +209,19 @@ func (relayTx *RelayTransaction) ID() interface{} {
 	return nil
 }
 
+// IncreaseGasLimit ...
+// relay transactions already attach the maximum prepaid gas allowed for a
+// function call, so the limit cannot be raised any further
+func (relayTx *RelayTransaction) IncreaseGasLimit() (gasLimit uint64, err error) {
+	return gas, chain.ErrGasLimitCeilingReached
+}
+
 func (relayTx *RelayTransaction) Receipt(ctx context.Context) (blockHeight uint64, err error) {
 	var txStatus types.TransactionResult
 	if relayTx.Transaction.Txid == [32]byte{} {
 		return 0, fmt.Errorf("no pending tx")
 	}
+	relayTx.cost = nil
 
 	for i, isPending := 0, true; i < 5 && (isPending || errors.Is(err, errors.ErrUnknownTransaction)); i++ {
 		time.Sleep(time.Second)
@@ -217,12 +241,34 @@ func (relayTx *RelayTransaction) Receipt(ctx context.Context) (blockHeight uint6
 		}
 
 		blockHeight = uint64(block.Height())
+		relayTx.cost = txCost(txStatus)
 	}
 
 	//TODO: Handle errors
 	return blockHeight, err
 }
 
+func (relayTx *RelayTransaction) Cost() *chain.TxCost {
+	return relayTx.cost
+}
+
+// txCost ...
+// returns the gas and tokens burnt by the tx and the receipts it produced
+func txCost(txr types.TransactionResult) *chain.TxCost {
+	outcomes := append([]types.ExecutionOutcomeWithIdView{txr.TransactionOutcome}, txr.ReceiptsOutcome...)
+	cost := &chain.TxCost{Fee: new(big.Int)}
+	for _, o := range outcomes {
+		tokens := big.Int(o.Outcome.TokensBurnt)
+		cost.GasUsed += o.Outcome.GasBurnt
+		cost.Fee.Add(cost.Fee, &tokens)
+	}
+	cost.GasPrice = new(big.Int)
+	if cost.GasUsed > 0 {
+		cost.GasPrice.Div(cost.Fee, new(big.Int).SetUint64(cost.GasUsed))
+	}
+	return cost
+}
+
 func (relayTx *RelayTransaction) Send(ctx context.Context) (err error) {
 	relayTx.client.Logger().WithFields(log.Fields{"signer": relayTx.Transaction.SignerId}).Debug("prepare tx")
 	_ctx, cancel := context.WithTimeout(ctx, defaultSendTxTimeout)
@@ -276,3 +322,12 @@ func (s *Sender) Balance(ctx context.Context) (balance, threshold *big.Int, err
 
 	return balance, &t, err
 }
+
+// Close ...
+// closes the clients
+func (s *Sender) Close() error {
+	for _, client := range s.clients {
+		client.Close()
+	}
+	return nil
+}
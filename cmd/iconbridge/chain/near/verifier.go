@@ -1,6 +1,7 @@
 package near
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"sync"
@@ -54,10 +55,13 @@ func NewVerifier(blockHeight uint64, previousBlockHash, currentEpochId, nextEpoc
 	return v, nil
 }
 
-func (v *Verifier) SyncHeader(wg *sync.WaitGroup, target uint64) error {
+func (v *Verifier) SyncHeader(ctx context.Context, wg *sync.WaitGroup, target uint64) error {
 	defer wg.Done()
 
-	err := v.client.MonitorBlocks(v.blockHeight, v.SyncConcurrency, func(observable rxgo.Observable) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	err := v.client.MonitorBlocks(ctx, v.blockHeight, v.SyncConcurrency, func(observable rxgo.Observable) error {
 		result := observable.Serialize(int(v.blockHeight),
 			func(bn interface{}) int {
 				return int(bn.(*types.BlockNotification).Offset())
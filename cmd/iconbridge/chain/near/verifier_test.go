@@ -1,6 +1,7 @@
 package near
 
 import (
+	"context"
 	"sync"
 	"testing"
 
@@ -33,7 +34,7 @@ func TestNearVerifier(t *testing.T) {
 					wg := new(sync.WaitGroup)
 					wg.Add(1)
 
-					err = verifier.SyncHeader(wg, input.Offset-1)
+					err = verifier.SyncHeader(context.Background(), wg, input.Offset-1)
 					require.Nil(f, err)
 
 					wg.Wait()
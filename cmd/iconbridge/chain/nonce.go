@@ -0,0 +1,43 @@
+package chain
+
+import "sync"
+
+// NonceTracker ...
+// assigns consecutive nonces to the txs of an account sent before the
+// previous ones are mined, so that they are executed in order
+type NonceTracker struct {
+	mtx  sync.Mutex
+	next uint64 // zero if unknown
+}
+
+// Next ...
+// reserves the nonce of the next tx, "onchain" is the account nonce
+// of the latest block
+func (t *NonceTracker) Next(onchain uint64) uint64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	nonce := onchain
+	if t.next > nonce {
+		nonce = t.next
+	}
+	t.next = nonce + 1
+	return nonce
+}
+
+// Release ...
+// gives back "nonce" if its tx wasn't sent and no later one was reserved
+func (t *NonceTracker) Release(nonce uint64) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.next == nonce+1 {
+		t.next = nonce
+	}
+}
+
+// Reset ...
+// forgets the reserved nonces, the next tx takes the nonce on chain
+func (t *NonceTracker) Reset() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.next = 0
+}
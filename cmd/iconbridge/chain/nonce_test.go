@@ -0,0 +1,31 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonceTracker(t *testing.T) {
+	var nt NonceTracker
+
+	// txs sent before the previous ones are mined take consecutive nonces
+	require.Equal(t, uint64(5), nt.Next(5))
+	require.Equal(t, uint64(6), nt.Next(5))
+	require.Equal(t, uint64(7), nt.Next(6))
+
+	// the account nonce wins once it's ahead, e.g. after another sender
+	require.Equal(t, uint64(9), nt.Next(9))
+
+	// only the last nonce reserved can be given back
+	nt.Release(9)
+	require.Equal(t, uint64(9), nt.Next(5))
+	require.Equal(t, uint64(10), nt.Next(5))
+	nt.Release(9)
+	require.Equal(t, uint64(11), nt.Next(5))
+
+	// after a reset, the next tx takes the account nonce
+	nt.Reset()
+	require.Equal(t, uint64(5), nt.Next(5))
+	require.Equal(t, uint64(6), nt.Next(5))
+}
@@ -0,0 +1,34 @@
+package chain
+
+import "time"
+
+const (
+	DefaultScanReportInterval = 10 * time.Second
+)
+
+// ScanReport ...
+// builds the messages a Receiver sends for the blocks it scanned: one for
+// each block with receipts, and one without receipts at most every
+// Interval otherwise, so that the relay keeps track of quiet sources too
+type ScanReport struct {
+	Interval time.Duration // DefaultScanReportInterval if zero
+	last     time.Time
+}
+
+// Message ...
+// returns the message for the block at "height" with "receipts", or nil
+// if the block has none and the last report is recent
+func (s *ScanReport) Message(height uint64, receipts []*Receipt) *Message {
+	now := time.Now()
+	if len(receipts) == 0 {
+		interval := s.Interval
+		if interval == 0 {
+			interval = DefaultScanReportInterval
+		}
+		if now.Sub(s.last) < interval {
+			return nil
+		}
+	}
+	s.last = now
+	return &Message{Receipts: receipts, Height: height}
+}
@@ -0,0 +1,29 @@
+package chain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanReport(t *testing.T) {
+	s := &ScanReport{Interval: time.Hour}
+
+	msg := s.Message(10, nil)
+	require.NotNil(t, msg)
+	require.EqualValues(t, 10, msg.Height)
+	require.Empty(t, msg.Receipts)
+
+	require.Nil(t, s.Message(11, nil))
+
+	receipts := []*Receipt{{Height: 12, Events: []*Event{{Sequence: 1}}}}
+	msg = s.Message(12, receipts)
+	require.NotNil(t, msg)
+	require.EqualValues(t, 12, msg.Height)
+	require.Equal(t, receipts, msg.Receipts)
+
+	s.Interval = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	require.NotNil(t, s.Message(13, nil))
+}
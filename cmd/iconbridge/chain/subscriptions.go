@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscriptions ...
+// runs the subscription goroutines of a Receiver, so that its Close can
+// terminate them and wait until they've returned
+type Subscriptions struct {
+	mtx     sync.Mutex
+	wg      sync.WaitGroup
+	closed  bool
+	nextID  int
+	cancels map[int]context.CancelFunc
+}
+
+// Go ...
+// runs "fn" in a goroutine with a context cancelled when "ctx" is done,
+// or on Close; returns ErrClosed once closed
+func (s *Subscriptions) Go(ctx context.Context, fn func(ctx context.Context)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.cancels == nil {
+		s.cancels = make(map[int]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	id := s.nextID
+	s.nextID++
+	s.cancels[id] = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.done(id)
+		fn(ctx)
+	}()
+	return nil
+}
+
+func (s *Subscriptions) done(id int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+		delete(s.cancels, id)
+	}
+}
+
+// Close ...
+// cancels the running subscriptions and waits until they've returned
+func (s *Subscriptions) Close() {
+	s.mtx.Lock()
+	s.closed = true
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mtx.Unlock()
+	s.wg.Wait()
+}
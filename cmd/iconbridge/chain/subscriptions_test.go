@@ -0,0 +1,29 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionsClose(t *testing.T) {
+	var subs Subscriptions
+	returned := make(chan struct{}, 2)
+	run := func(ctx context.Context) {
+		<-ctx.Done()
+		returned <- struct{}{}
+	}
+	assert.NoError(t, subs.Go(context.Background(), run))
+	assert.NoError(t, subs.Go(context.Background(), run))
+
+	// a subscription cancelled by its context is forgotten
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, subs.Go(ctx, func(ctx context.Context) { <-ctx.Done() }))
+	cancel()
+
+	subs.Close()
+	assert.Len(t, returned, 2)
+	assert.Empty(t, subs.cancels)
+	assert.ErrorIs(t, subs.Go(context.Background(), run), ErrClosed)
+}
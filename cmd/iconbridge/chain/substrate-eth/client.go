@@ -67,6 +67,7 @@ type IClient interface {
 	GetChainID() *big.Int
 	GetEthClient() *ethclient.Client
 	Log() log.Logger
+	Close()
 }
 
 func (cl *Client) GetBalance(ctx context.Context, hexAddr string) (*big.Int, error) {
@@ -221,3 +222,9 @@ func (c *Client) GetEthClient() *ethclient.Client {
 func (c *Client) Log() log.Logger {
 	return c.log
 }
+
+// Close ...
+// closes the rpc connection of the client
+func (c *Client) Close() {
+	c.rpc.Close()
+}
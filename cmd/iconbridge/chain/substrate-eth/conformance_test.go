@@ -0,0 +1,299 @@
+package substrate_eth
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/chaintest"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain/substrate-eth/abi"
+	subEthTypes "github.com/icon-project/icon-bridge/cmd/iconbridge/chain/substrate-eth/types"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/icon-project/icon-bridge/common/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	conformanceSNOW = chain.BTPAddress("btp://0x228.snow/0xACBA72f72a56A15dBBFFFD638999Fd80A89A19Cf")
+	conformanceICON = chain.BTPAddress("btp://0x2.icon/cxfa5b7b8d71bf6355bf230ff22e1d4777a630c7ec")
+)
+
+// fakeEth ...
+// is the eth json-rpc api of the fake node: it serves the bmc logs of the
+// blocks with receipts, includes the txs it's sent with "receipt", and
+// answers calls with "call"; sending fails with "sendErr" if set
+type fakeEth struct {
+	logs    map[uint64][]ethTypes.Log
+	sendErr error
+	receipt *ethTypes.Receipt
+	call    []byte
+	sent    *ethTypes.Transaction
+}
+
+func (e *fakeEth) GetLogs(q map[string]interface{}) ([]ethTypes.Log, error) {
+	height, err := hexutil.DecodeUint64(q["fromBlock"].(string))
+	if err != nil {
+		return nil, err
+	}
+	return append([]ethTypes.Log{}, e.logs[height]...), nil
+}
+
+func (e *fakeEth) GetTransactionCount(addr ethCommon.Address, block string) (hexutil.Uint64, error) {
+	return 1, nil
+}
+
+func (e *fakeEth) SendRawTransaction(b hexutil.Bytes) (ethCommon.Hash, error) {
+	if e.sendErr != nil {
+		return ethCommon.Hash{}, e.sendErr
+	}
+	e.sent = new(ethTypes.Transaction)
+	if err := e.sent.UnmarshalBinary(b); err != nil {
+		return ethCommon.Hash{}, err
+	}
+	return e.sent.Hash(), nil
+}
+
+func (e *fakeEth) GetTransactionByHash(h ethCommon.Hash) (map[string]interface{}, error) {
+	b, err := e.sent.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	tx := make(map[string]interface{})
+	if err := json.Unmarshal(b, &tx); err != nil {
+		return nil, err
+	}
+	tx["blockNumber"] = hexutil.EncodeBig(e.receipt.BlockNumber)
+	return tx, nil
+}
+
+func (e *fakeEth) GetTransactionReceipt(h ethCommon.Hash) (*ethTypes.Receipt, error) {
+	r := *e.receipt
+	r.TxHash, r.Logs = h, []*ethTypes.Log{}
+	return &r, nil
+}
+
+func (e *fakeEth) Call(msg map[string]interface{}, block string) (hexutil.Bytes, error) {
+	return e.call, nil
+}
+
+// fakeClient ...
+// is a client of the fake node, serving the blocks of the receipts it's
+// made of, and empty blocks around them
+type fakeClient struct {
+	eth      *ethclient.Client
+	receipts map[uint64]ethTypes.Receipts
+	last     uint64
+}
+
+// newFakeNode ...
+// returns a client serving the events of "msg" as bmc logs, in blocks at
+// the heights of its receipts, and the eth api it's connected to
+func newFakeNode(t *testing.T, msg *chain.Message) (*fakeClient, *fakeEth) {
+	parsed, err := ethabi.JSON(strings.NewReader(abi.BMCABI))
+	require.NoError(t, err)
+	event := parsed.Events["Message"]
+	bmc := ethCommon.HexToAddress(conformanceSNOW.ContractAddress())
+
+	e := &fakeEth{logs: make(map[uint64][]ethTypes.Log)}
+	cl := &fakeClient{receipts: make(map[uint64]ethTypes.Receipts)}
+	for _, receipt := range msg.Receipts {
+		txr := &ethTypes.Receipt{Status: ethTypes.ReceiptStatusSuccessful, Logs: []*ethTypes.Log{}}
+		for _, ev := range receipt.Events {
+			data, err := event.Inputs.Pack(conformanceICON.String(), new(big.Int).SetUint64(ev.Sequence), ev.Message)
+			require.NoError(t, err)
+			l := ethTypes.Log{Address: bmc, Topics: []ethCommon.Hash{event.ID}, Data: data, BlockNumber: receipt.Height}
+			txr.Logs = append(txr.Logs, &l)
+			e.logs[receipt.Height] = append(e.logs[receipt.Height], l)
+		}
+		cl.receipts[receipt.Height] = append(cl.receipts[receipt.Height], txr)
+		cl.last = receipt.Height
+	}
+
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("eth", e))
+	cl.eth = ethclient.NewClient(rpc.DialInProc(srv))
+	return cl, e
+}
+
+func (cl *fakeClient) GetBalance(ctx context.Context, hexAddr string) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (cl *fakeClient) GetBlockNumber() (uint64, error) {
+	return cl.last + 2 + BlockFinalityConfirmations, nil
+}
+
+func (cl *fakeClient) GetHeaderByHeight(height *big.Int) (*subEthTypes.Header, error) {
+	h := &subEthTypes.Header{Number: height}
+	binary.BigEndian.PutUint64(h.Hash[24:], height.Uint64())
+	binary.BigEndian.PutUint64(h.ParentHash[24:], height.Uint64()-1)
+	if receipts, ok := cl.receipts[height.Uint64()]; ok {
+		h.GasUsed = 1
+		h.ReceiptHash = ethTypes.DeriveSha(receipts, trie.NewStackTrie(nil))
+	}
+	return h, nil
+}
+
+func (cl *fakeClient) GetBlockReceiptsFromHeight(height *big.Int) (ethTypes.Receipts, bool, error) {
+	return cl.receipts[height.Uint64()], false, nil
+}
+
+func (cl *fakeClient) GetChainID() *big.Int            { return big.NewInt(552) }
+func (cl *fakeClient) GetEthClient() *ethclient.Client { return cl.eth }
+func (cl *fakeClient) Log() log.Logger                 { return log.New() }
+func (cl *fakeClient) Close()                          { cl.eth.Close() }
+
+// newConformanceSender ...
+// returns a sender relaying from icon to the fake node "cl"
+func newConformanceSender(t *testing.T, cl *fakeClient, sizeLimit uint64) *sender {
+	privKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	bmc, err := abi.NewBMC(ethCommon.HexToAddress(conformanceSNOW.ContractAddress()), cl.eth)
+	require.NoError(t, err)
+	return &sender{
+		log:          log.New(),
+		w:            &wallet.EvmWallet{Skey: privKey, Pkey: &privKey.PublicKey},
+		src:          conformanceICON,
+		dst:          conformanceSNOW,
+		opts:         senderOptions{GasLimit: 24000000, TxDataSizeLimit: sizeLimit},
+		cls:          []IClient{cl},
+		bmcs:         []*abi.BMC{bmc},
+		prevGasPrice: big.NewInt(defaultGasPrice),
+	}
+}
+
+// sendTx ...
+// sends a relay tx to a fake node, which rejects it with "sendErr", or
+// includes it with "txr" and answers calls with "data"; returns the error
+// of the tx
+func sendTx(ctx context.Context, t *testing.T, sendErr error, txr *ethTypes.Receipt, data []byte) error {
+	cl, e := newFakeNode(t, &chain.Message{})
+	defer cl.Close()
+	e.sendErr, e.receipt, e.call = sendErr, txr, data
+	tx, _, err := newConformanceSender(t, cl, 0).Segment(
+		ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+	if err != nil {
+		return err
+	}
+	if err := tx.Send(ctx); err != nil {
+		return err
+	}
+	_, err = tx.Receipt(ctx)
+	return err
+}
+
+// revertData ...
+// returns the result of a call reverted with "reason"
+func revertData(reason string) []byte {
+	data := make([]byte, 4+32+32, 4+32+32+len(reason))
+	binary.BigEndian.PutUint64(data[4+32+24:], uint64(len(reason)))
+	return append(data, reason...)
+}
+
+func TestConformance_Segment(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceICON, 100, 10, 20, 3, 100)
+	cl, _ := newFakeNode(t, &chain.Message{})
+	defer cl.Close()
+	for name, opts := range map[string]chain.SegmentOptions{
+		"default":     {},
+		"maxReceipts": {MaxReceipts: 2},
+		"maxSize":     {MaxSize: 512},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newConformanceSender(t, cl, 2048)
+			txs := chaintest.Segment(t, s, msg, opts)
+			require.Greater(t, len(txs), 1)
+		})
+	}
+}
+
+func TestConformance_Subscribe(t *testing.T) {
+	msg := chaintest.NewMessage(conformanceSNOW, 102, 10, 5, 3, 10)
+	cl, _ := newFakeNode(t, msg)
+	bmc, err := abi.NewBMC(ethCommon.HexToAddress(conformanceSNOW.ContractAddress()), cl.eth)
+	require.NoError(t, err)
+	r := &receiver{
+		log:  log.New(),
+		src:  conformanceSNOW,
+		dst:  conformanceICON,
+		opts: ReceiverOptions{SyncConcurrency: 4},
+		cls:  []IClient{cl},
+		bmcs: []*abi.BMC{bmc},
+	}
+	msgs := chaintest.Subscribe(t, r, chain.SubscribeOptions{Seq: 11, Height: 100}, 13, time.Minute)
+	receipts := chaintest.Receipts(msgs)
+	require.NotEmpty(t, receipts)
+	require.Equal(t, uint64(12), receipts[0].Events[0].Sequence)
+	chaintest.Close(t, r)
+}
+
+func TestConformance_Errors(t *testing.T) {
+	chaintest.Errors(t, []chaintest.ErrorCase{
+		{
+			Name: "InsufficientBalance",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, errors.New("insufficient funds for gas * price + value"), nil, nil)
+			},
+			Want: chain.ErrInsufficientBalance,
+		},
+		{
+			Name: "BlockGasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, errors.New("exceeds block gas limit"), nil, nil)
+			},
+			Want: chain.ErrBlockGasLimitExceeded,
+		},
+		{
+			Name: "GasLimitCeilingReached",
+			Call: func(ctx context.Context) error {
+				cl, _ := newFakeNode(t, &chain.Message{})
+				defer cl.Close()
+				tx, _, err := newConformanceSender(t, cl, 0).Segment(
+					ctx, chaintest.NewMessage(conformanceICON, 1, 1, 1, 1, 10), chain.SegmentOptions{})
+				if err != nil {
+					return err
+				}
+				for {
+					if _, err := tx.IncreaseGasLimit(); err != nil {
+						return err
+					}
+				}
+			},
+			Want: chain.ErrGasLimitCeilingReached,
+		},
+		{
+			Name: "GasLimitExceeded",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, nil, &ethTypes.Receipt{
+					GasUsed:     24000000,
+					BlockNumber: big.NewInt(1),
+				}, nil)
+			},
+			Want: chain.ErrGasLimitExceeded,
+		},
+		{
+			Name: "Revert",
+			Call: func(ctx context.Context) error {
+				return sendTx(ctx, t, nil, &ethTypes.Receipt{
+					GasUsed:     100000,
+					BlockNumber: big.NewInt(1),
+				}, revertData("InvalidSeqNumber"))
+			},
+			Want: chain.ErrBMCRevertInvalidSeqNumber,
+		},
+	})
+}
@@ -5,4 +5,8 @@ import "github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
 func init() {
 	relay.Senders["snow"] = NewSender
 	relay.Receivers["snow"] = NewReceiver
+	relay.LoopDefaults["snow"] = relay.LoopConfig{
+		TickerInterval:        12, // 12s blocks
+		TxReceiptWaitInterval: 3,
+	}
 }
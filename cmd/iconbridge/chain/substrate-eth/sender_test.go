@@ -0,0 +1,28 @@
+package substrate_eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender_IncreaseGasLimit(t *testing.T) {
+	tx := &relayTx{
+		opts:        &bind.TransactOpts{GasLimit: 1000},
+		maxGasLimit: 2000,
+		pendingTx:   ethtypes.NewTx(&ethtypes.LegacyTx{}),
+	}
+	for _, want := range []uint64{1250, 1562, 1952, 2000} {
+		got, err := tx.IncreaseGasLimit()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Equal(t, want, tx.opts.GasLimit)
+		require.Nil(t, tx.pendingTx, "tx must be sent again")
+	}
+	got, err := tx.IncreaseGasLimit()
+	require.ErrorIs(t, err, chain.ErrGasLimitCeilingReached)
+	require.Equal(t, uint64(2000), got)
+}
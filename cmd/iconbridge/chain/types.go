@@ -32,6 +32,11 @@ type Message struct {
 	From     BTPAddress
 	Receipts []*Receipt
 	// Headers  []interface{}
+
+	// Height ...
+	// is the last source height scanned by the receiver, if set; it sends
+	// messages without receipts to report the scan of blocks without events
+	Height uint64
 }
 
 type BMCLinkStatus struct {
@@ -54,6 +59,24 @@ type RelayTx interface {
 	ID() interface{}
 	Send(ctx context.Context) (err error)
 	Receipt(ctx context.Context) (blockHeight uint64, err error)
+
+	// IncreaseGasLimit ...
+	// rebuilds the tx with a higher gas/step limit, bounded by the ceiling
+	// configured on the sender, so that it can be sent again; returns
+	// ErrGasLimitCeilingReached if the limit cannot be raised any further
+	IncreaseGasLimit() (gasLimit uint64, err error)
+
+	// Size ...
+	// returns the size of the encoded relay message in bytes
+	Size() int
+}
+
+// SegmentOptions ...
+// limits the receipts packed into a single relay tx by Sender.Segment;
+// zero values leave the corresponding limit up to the sender
+type SegmentOptions struct {
+	MaxReceipts uint64 // maximum number of receipts
+	MaxSize     uint64 // maximum size of the encoded receipts in bytes
 }
 
 type SubscribeOptions struct {
@@ -61,12 +84,52 @@ type SubscribeOptions struct {
 	Height uint64
 }
 
+// TxCost ...
+// is the fee paid for a relay tx, in the smallest unit of the native coin
+// of the destination chain
+type TxCost struct {
+	GasUsed  uint64   // gas, steps or NEAR gas burnt
+	GasPrice *big.Int // per unit of GasUsed
+	Fee      *big.Int
+}
+
+// NewTxCost ...
+// returns the cost of a tx which used "gasUsed" units at "gasPrice"
+func NewTxCost(gasUsed uint64, gasPrice *big.Int) *TxCost {
+	if gasPrice == nil {
+		gasPrice = new(big.Int)
+	}
+	return &TxCost{
+		GasUsed:  gasUsed,
+		GasPrice: gasPrice,
+		Fee:      new(big.Int).Mul(new(big.Int).SetUint64(gasUsed), gasPrice),
+	}
+}
+
+// CostedRelayTx ...
+// is a RelayTx that reports what it cost once it's included
+type CostedRelayTx interface {
+	RelayTx
+
+	// Cost ...
+	// returns the cost of the tx found by the last call to Receipt,
+	// nil if the tx wasn't found included
+	Cost() *TxCost
+}
+
 type Receiver interface {
 	// Subscribe ...
 	// subscribes to BTP messages and block headers on `msgCh` of the src chain
 	// and returns an `errCh` that sends any error during subscription and terminates
-	// the subscription by closing `errCh`
+	// the subscription by closing `errCh`; messages report the height scanned,
+	// see ScanReport
 	Subscribe(ctx context.Context, msgCh chan<- *Message, opts SubscribeOptions) (errCh <-chan error, err error)
+
+	// Close ...
+	// terminates the subscriptions, waits until their goroutines have
+	// returned and releases the connections of the receiver; it can't
+	// subscribe afterwards
+	Close() error
 }
 
 type Sender interface {
@@ -75,15 +138,34 @@ type Sender interface {
 	Status(ctx context.Context) (link *BMCLinkStatus, err error)
 
 	// Segment ...
-	// returns a "tx" Tx object including events upto "txSizeLimit" bytes and
-	// returns rest of the "msg" Message as "newMsg"
-	Segment(ctx context.Context, msg *Message) (tx RelayTx, newMsg *Message, err error)
+	// returns a "tx" Tx object including events upto "txSizeLimit" bytes, or
+	// fewer if limited by "opts", and returns rest of the "msg" Message as "newMsg"
+	Segment(ctx context.Context, msg *Message, opts SegmentOptions) (tx RelayTx, newMsg *Message, err error)
 
 	// Returns the current relayer balance
 	Balance(ctx context.Context) (balance, threshold *big.Int, err error)
+
+	// Close ...
+	// releases the connections of the sender, which can't be used afterwards
+	Close() error
 }
 
+// PipelinedSender ...
+// is a Sender whose relay txs can be sent before the previous ones are
+// confirmed, and are executed in the order they were sent
+type PipelinedSender interface {
+	Sender
+
+	// ResetPipeline ...
+	// forgets the txs in flight after one of them failed, so that the next
+	// tx follows the last confirmed state of the destination
+	ResetPipeline()
+}
+
+// Relayer ...
+// is both a Sender and a Receiver, spelled out as the module's go version
+// doesn't allow embedding both with their Close methods
 type Relayer interface {
 	Sender
-	Receiver
+	Subscribe(ctx context.Context, msgCh chan<- *Message, opts SubscribeOptions) (errCh <-chan error, err error)
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
+)
+
+// runCosts ...
+// reports the fees paid for relay txs recorded in the journal, by relay
+// and optionally by day, converted with the configured coin prices
+func runCosts(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("costs", flag.ExitOnError)
+	name := fs.String("relay", "", "relay name, all the configured relays if empty")
+	from := fs.String("from", "", "start time, RFC3339")
+	to := fs.String("to", "", "end time, RFC3339")
+	daily := fs.Bool("daily", false, "report costs per day")
+	asJSON := fs.Bool("json", false, "print the report as json")
+	fs.Parse(args)
+
+	q := &relay.JournalQuery{Limit: math.MaxInt32}
+	var err error
+	if *from != "" {
+		if q.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("invalid from: %v", err)
+		}
+	}
+	if *to != "" {
+		if q.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("invalid to: %v", err)
+		}
+	}
+	chains := make(map[string]string, len(cfg.Relays))
+	var names []string
+	for _, rc := range cfg.Relays {
+		if *name == "" || rc.Name == *name {
+			chains[rc.Name] = rc.Dst.Address.BlockChain()
+			names = append(names, rc.Name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("relay not found: %q", *name)
+	}
+
+	query, closeDB, err := journalQuerier(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	var entries []*relay.JournalEntry
+	for _, name := range names {
+		q.Relay = name
+		es, err := query(q)
+		if err != nil {
+			return fmt.Errorf("relay %s: %v", name, err)
+		}
+		entries = append(entries, es...)
+	}
+	rep := relay.NewCostReport(entries, *daily, cfg.Costs, chains)
+	rep.JournalLimit = cfg.Journal.Limit()
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+	fmt.Printf("costs of the txs in the journal, which keeps the latest %d entries per relay\n", rep.JournalLimit)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "RELAY\tSINCE\tDAY\tTXS\tFAILED\tMESSAGES\tGAS\tFEE\tFEE/MSG\tCOINS\tVALUE %s\tVALUE/MSG\n", rep.Unit)
+	for _, row := range rep.Rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%v\t%v\t%.6f %s\t%.4f\t%.6f\n",
+			row.Relay, time.Unix(0, row.Since).UTC().Format(time.RFC3339), row.Day, row.Txs, row.Failed, row.Messages, row.GasUsed,
+			row.Fee, row.FeePerMessage, row.Coins, row.Symbol, row.Value, row.PerMessage)
+	}
+	return w.Flush()
+}
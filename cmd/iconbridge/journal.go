@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
+	"github.com/icon-project/icon-bridge/common/db"
+)
+
+// runJournal ...
+// queries the relay journal; it's read from the relay db, or from the admin
+// server of the running relay if the db is locked by it
+func runJournal(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("journal", flag.ExitOnError)
+	name := fs.String("relay", "", "relay name, all the configured relays if empty")
+	seq := fs.Uint64("seq", 0, "event sequence carried by the relay tx")
+	from := fs.String("from", "", "start time, RFC3339")
+	to := fs.String("to", "", "end time, RFC3339")
+	tx := fs.String("tx", "", "relay tx id or hash")
+	limit := fs.Int("limit", 0, "latest entries per relay")
+	asJSON := fs.Bool("json", false, "print entries as json")
+	fs.Parse(args)
+
+	q := &relay.JournalQuery{Seq: *seq, TxID: *tx, Limit: *limit}
+	var err error
+	if *from != "" {
+		if q.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("invalid from: %v", err)
+		}
+	}
+	if *to != "" {
+		if q.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("invalid to: %v", err)
+		}
+	}
+	names := []string{*name}
+	if *name == "" {
+		names = names[:0]
+		for _, rc := range cfg.Relays {
+			names = append(names, rc.Name)
+		}
+	}
+
+	query, closeDB, err := journalQuerier(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	var entries []*relay.JournalEntry
+	for _, name := range names {
+		q.Relay = name
+		es, err := query(q)
+		if err != nil {
+			return fmt.Errorf("relay %s: %v", name, err)
+		}
+		entries = append(entries, es...)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tRELAY\tACTION\tSEQ\tRECEIPTS\tSIZE\tTX\tHEIGHT\tRETRY\tERROR")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d-%d\t%d\t%d\t%s\t%d\t%d\t%s\n",
+			time.Unix(0, e.Time).UTC().Format(time.RFC3339Nano), e.Relay, e.Action,
+			e.SeqBegin, e.SeqEnd, e.Receipts, e.Size, e.TxID, e.Height, e.Retry, e.Error)
+	}
+	return w.Flush()
+}
+
+// journalQuerier ...
+// returns a function querying the journal in the relay db, or through
+// the admin server if the db can't be opened
+func journalQuerier(cfg *Config) (query func(q *relay.JournalQuery) ([]*relay.JournalEntry, error), closeDB func(), err error) {
+	if cfg.DB == nil {
+		return nil, nil, relay.ErrJournalDisabled
+	}
+	database, err := db.Open(cfg.DB.Dir, cfg.DB.Type, cfg.DB.Name)
+	if err != nil {
+		if cfg.Admin == nil {
+			return nil, nil, fmt.Errorf("db.Open type %v err %v", cfg.DB.Type, err)
+		}
+		return adminJournalQuerier(cfg.Admin.Address), func() {}, nil
+	}
+	js, err := relay.NewJournalStore(database, cfg.Journal)
+	if err != nil {
+		database.Close()
+		return nil, nil, err
+	}
+	return js.Query, func() { database.Close() }, nil
+}
+
+func adminJournalQuerier(address string) func(q *relay.JournalQuery) ([]*relay.JournalEntry, error) {
+	return func(q *relay.JournalQuery) ([]*relay.JournalEntry, error) {
+		resp, err := http.Get(fmt.Sprintf("http://%s/journal?%s", address, q.Values().Encode()))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			var msg struct {
+				Message string `json:"message"`
+			}
+			json.NewDecoder(resp.Body).Decode(&msg)
+			return nil, fmt.Errorf("admin server: %s %s", resp.Status, msg.Message)
+		}
+		var entries []*relay.JournalEntry
+		err = json.NewDecoder(resp.Body).Decode(&entries)
+		return entries, err
+	}
+}
@@ -8,12 +8,17 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"syscall"
+	"time"
 
 	_ "net/http/pprof"
 
+	"github.com/labstack/echo/v4"
+
 	"github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
 	"github.com/icon-project/icon-bridge/cmd/iconbridge/stat"
+	"github.com/icon-project/icon-bridge/common"
 	"github.com/icon-project/icon-bridge/common/config"
 	"github.com/icon-project/icon-bridge/common/log"
 
@@ -40,6 +45,12 @@ type Config struct {
 	LogWriter         *log.WriterConfig    `json:"log_writer,omitempty"`
 	LogForwarder      *log.ForwarderConfig `json:"log_forwarder,omitempty"`
 	StatConfig        *stat.StatConfig     `json:"stat_collector,omitempty"`
+	Metrics           *MetricsConfig       `json:"metrics,omitempty"`
+	Admin             *relay.AdminConfig   `json:"admin,omitempty"`
+}
+
+type MetricsConfig struct {
+	Address string `json:"address"` // e.g. "0.0.0.0:9100"
 }
 
 func main() {
@@ -51,6 +62,10 @@ func main() {
 	}
 
 	l := setLogger(cfg)
+	if flag.NArg() > 0 {
+		runCommand(cfg, flag.Arg(0), flag.Args()[1:], l)
+		return
+	}
 	relay, err := relay.NewMultiRelay(&cfg.Config, l)
 	if err != nil {
 		log.Fatalf("failed to create MultiRelay: %v", err)
@@ -65,10 +80,39 @@ func main() {
 	}
 	// for net/http/pprof
 	go func() { http.ListenAndServe("0.0.0.0:6060", nil) }()
-	runRelay(relay, scollector)
+	if cfg.Metrics != nil {
+		startMetricsServer(cfg.Metrics, l)
+	}
+	reload := reloadConfig(cfg, relay, l)
+	if cfg.Admin != nil {
+		startAdminServer(cfg.Admin, relay, reload, l)
+	}
+	runRelay(relay, scollector, reload, cfg.ShutdownDeadline())
+}
+
+// runCommand ...
+// runs the subcommand "cmd" instead of the relays
+func runCommand(cfg *Config, cmd string, args []string, l log.Logger) {
+	var err error
+	switch cmd {
+	case "journal":
+		err = runJournal(cfg, args)
+	case "replay":
+		err = runReplay(cfg, args, l)
+	case "costs":
+		err = runCosts(cfg, args)
+	default:
+		log.Fatalf("unknown command: %s", cmd)
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", cmd, err)
+	}
 }
 
-func runRelay(relay relay.Relay, sc stat.StatCollector) {
+// runRelay ...
+// runs the relays until the first signal, which drains them within
+// "shutdown" before cancelling them; a second signal exits right away
+func runRelay(relay relay.MultiRelay, sc stat.StatCollector, reload func() error, shutdown time.Duration) {
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -81,14 +125,36 @@ func runRelay(relay relay.Relay, sc stat.StatCollector) {
 
 	go func() {
 		select {
-		case <-sigCh: // first signal, cancel context
-			cancel()
+		case <-sigCh: // first signal, drain the relays then cancel context
+			go func() {
+				log.Infof("draining relays before exit: deadline %v, signal again to exit now", shutdown)
+				dctx, dcancel := context.WithTimeout(ctx, shutdown)
+				defer dcancel()
+				relay.Shutdown(dctx)
+				cancel()
+			}()
 		case <-ctx.Done():
 		}
 		<-sigCh // second signal, hard exit
 		os.Exit(2)
 	}()
 
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+	go func() {
+		for {
+			select {
+			case <-hupCh:
+				if err := reload(); err != nil {
+					log.Errorf("failed to reload config: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	if err := sc.Start(ctx); err != nil {
 		log.Error(err)
 	}
@@ -99,6 +165,55 @@ func runRelay(relay relay.Relay, sc stat.StatCollector) {
 	}
 }
 
+func startMetricsServer(cfg *MetricsConfig, l log.Logger) {
+	e := echo.New()
+	e.HideBanner, e.HidePort = true, true
+	e.GET("/metrics", echo.WrapHandler(relay.MetricsHandler()))
+	srv := common.NewHttpServer(cfg.Address, e)
+	go func() {
+		l.Infof("metrics server listening on %s", srv.Address())
+		if err := srv.Start(); err != nil {
+			l.Errorf("metrics server terminated: %v", err)
+		}
+	}()
+}
+
+func startAdminServer(cfg *relay.AdminConfig, mr relay.MultiRelay, reload func() error, l log.Logger) {
+	srv := relay.NewAdminServer(cfg, mr, reload, l)
+	go func() {
+		l.Infof("admin server listening on %s", srv.Address())
+		if err := srv.Start(); err != nil {
+			l.Errorf("admin server terminated: %v", err)
+		}
+	}()
+}
+
+// reloadConfig ...
+// returns a function re-reading the relays from the config file, only
+// the relays whose config changed are restarted; the servers started by
+// "running" are left unchanged
+func reloadConfig(running *Config, mr relay.MultiRelay, l log.Logger) func() error {
+	return func() error {
+		l.Infof("reloading config: file=%q", cfgFile)
+		cfg, err := loadConfig(cfgFile)
+		if err != nil {
+			return err
+		}
+		var sections []string
+		if !reflect.DeepEqual(running.Metrics, cfg.Metrics) {
+			sections = append(sections, "metrics")
+		}
+		if !reflect.DeepEqual(running.Admin, cfg.Admin) {
+			sections = append(sections, "admin")
+		}
+		if len(sections) > 0 {
+			l.WithFields(log.Fields{"sections": sections}).Warn(
+				"config reloaded without the changes of the servers started at start, restart to apply them")
+		}
+		return mr.Reload(&cfg.Config)
+	}
+}
+
 func loadConfig(file string) (*Config, error) {
 	f, err := os.Open(file)
 	if err != nil {
@@ -109,6 +224,12 @@ func loadConfig(file string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.DB != nil {
+		cfg.DB.Dir = cfg.ResolveAbsolute(cfg.DB.Dir)
+	}
+	if cfg.Election != nil && cfg.Election.Dir != "" {
+		cfg.Election.Dir = cfg.ResolveAbsolute(cfg.Election.Dir)
+	}
 	return cfg, nil
 }
 
@@ -0,0 +1,268 @@
+package relay
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/icon-project/icon-bridge/common"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+const (
+	defaultAdminMaxLag         = 10 * time.Minute
+	defaultAdminEventKeepAlive = 15 * time.Second
+)
+
+type AdminConfig struct {
+	Address string `json:"address"`         // e.g. "127.0.0.1:9101"
+	MaxLag  uint   `json:"max_lag"`         // seconds a relay may hold undelivered messages and still be ready
+	Token   string `json:"token,omitempty"` // bearer token required by the control endpoints
+}
+
+// controlAllowed ...
+// returns whether the control endpoints can be served: they require a
+// token unless the server listens on the loopback interface only
+func (cfg *AdminConfig) controlAllowed() bool {
+	if cfg.Token != "" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// AdminServer ...
+// serves liveness, readiness and per-relay status of a MultiRelay,
+// so that an orchestrator can tell whether a relay is wedged
+type AdminServer struct {
+	*common.HttpServer
+	mr     MultiRelay
+	reload func() error
+	maxLag time.Duration
+	token  string
+	log    log.Logger
+}
+
+// NewAdminServer ...
+// "reload" re-reads the relay config, the reload endpoint is disabled if nil;
+// the control endpoints, which change what is relayed, require the token
+// of "cfg" if set, and are disabled if it isn't and the server is reachable
+// from other hosts
+func NewAdminServer(cfg *AdminConfig, mr MultiRelay, reload func() error, l log.Logger) *AdminServer {
+	e := echo.New()
+	e.HideBanner, e.HidePort = true, true
+	s := &AdminServer{
+		HttpServer: common.NewHttpServer(cfg.Address, e),
+		mr:         mr,
+		reload:     reload,
+		maxLag:     time.Duration(cfg.MaxLag) * time.Second,
+		token:      cfg.Token,
+		log:        l,
+	}
+	if s.maxLag == 0 {
+		s.maxLag = defaultAdminMaxLag
+	}
+	e.GET("/health", s.health)
+	e.GET("/ready", s.ready)
+	e.GET("/relays", s.relays)
+	e.GET("/relays/:name", s.relay)
+	e.GET("/journal", s.journal)
+	e.GET("/events", s.events)
+	if !cfg.controlAllowed() {
+		l.Warnf("admin: control endpoints disabled, set a token to serve them on %s", cfg.Address)
+		return s
+	}
+	ctl := e.Group("", s.authorize)
+	ctl.POST("/relays/:name/:action", s.control)
+	if reload != nil {
+		ctl.POST("/reload", s.reloadConfig)
+	}
+	return s
+}
+
+// authorize ...
+// rejects the requests without the bearer token, if one is configured
+func (s *AdminServer) authorize(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.token == "" {
+			return next(c)
+		}
+		token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+		}
+		return next(c)
+	}
+}
+
+func (s *AdminServer) health(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ready ...
+// responds with 503 and the reasons if any relay is not subscribed to its
+// source or has been holding undelivered messages for longer than maxLag
+func (s *AdminServer) ready(c echo.Context) error {
+	notReady := map[string]string{}
+	for _, st := range s.mr.Status() {
+		switch lag := time.Duration(st.Lag * float64(time.Second)); {
+		case !st.Subscribed:
+			notReady[st.Name] = "not subscribed"
+		case lag > s.maxLag:
+			notReady[st.Name] = fmt.Sprintf("lag %v exceeds %v", lag.Truncate(time.Second), s.maxLag)
+		}
+	}
+	if len(notReady) > 0 {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"ready":     false,
+			"not_ready": notReady,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"ready": true})
+}
+
+func (s *AdminServer) relays(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.mr.Status())
+}
+
+func (s *AdminServer) relay(c echo.Context) error {
+	name := c.Param("name")
+	for _, st := range s.mr.Status() {
+		if st.Name == name {
+			return c.JSON(http.StatusOK, st)
+		}
+	}
+	return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("relay not found: %s", name))
+}
+
+// control ...
+// pauses, resumes, restarts, drains, switches the mode of a relay or
+// releases its held message "seq", and responds with its status
+func (s *AdminServer) control(c echo.Context) error {
+	name, action := c.Param("name"), c.Param("action")
+	var err error
+	switch action {
+	case "pause":
+		err = s.mr.Pause(name)
+	case "resume":
+		err = s.mr.Resume(name)
+	case "restart":
+		err = s.mr.Restart(name)
+	case "drain":
+		err = s.mr.Drain(name)
+	case "shadow":
+		err = s.mr.SetShadow(name, true)
+	case "activate":
+		err = s.mr.SetShadow(name, false)
+	case "release":
+		seq, perr := strconv.ParseUint(c.QueryParam("seq"), 10, 64)
+		if perr != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid seq: %v", perr))
+		}
+		err = s.mr.Release(name, seq)
+	default:
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("unknown action: %s", action))
+	}
+	switch {
+	case errors.Is(err, ErrRelayNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	s.log.WithFields(log.Fields{"relay": name, "action": action}).Info("admin: relay control")
+	return s.relay(c)
+}
+
+// journal ...
+// responds with the journal entries selected by the query parameters
+// of JournalQuery.Values, of all the relays if "relay" is missing
+func (s *AdminServer) journal(c echo.Context) error {
+	q, err := ParseJournalQuery(c.QueryParams())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	entries, err := s.mr.Journal(q)
+	switch {
+	case errors.Is(err, ErrJournalDisabled):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// events ...
+// streams the lifecycle events of the relay named by the "relay" query
+// parameter, or of all the relays, as server-sent events until the client
+// disconnects; the stream is closed if the client falls behind
+func (s *AdminServer) events(c echo.Context) error {
+	name := c.QueryParam("relay")
+	if name != "" && !s.hasRelay(name) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("relay not found: %s", name))
+	}
+	events, cancel := s.mr.Events(name)
+	defer cancel()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	keepAlive := time.NewTicker(defaultAdminEventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			b, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Action, b); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+	}
+}
+
+func (s *AdminServer) hasRelay(name string) bool {
+	for _, st := range s.mr.Status() {
+		if st.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AdminServer) reloadConfig(c echo.Context) error {
+	if err := s.reload(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return s.relays(c)
+}
@@ -0,0 +1,121 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type statusRelay []*RelayStatus
+
+func (sr statusRelay) Start(ctx context.Context) error { return nil }
+func (sr statusRelay) Status() []*RelayStatus          { return sr }
+func (sr statusRelay) Pause(name string) error         { return nil }
+func (sr statusRelay) Resume(name string) error        { return nil }
+func (sr statusRelay) Restart(name string) error       { return nil }
+func (sr statusRelay) Drain(name string) error         { return nil }
+func (sr statusRelay) Reload(cfg *Config) error        { return nil }
+func (sr statusRelay) SetShadow(string, bool) error    { return nil }
+func (sr statusRelay) Release(string, uint64) error    { return nil }
+func (sr statusRelay) Shutdown(context.Context) error  { return nil }
+func (sr statusRelay) Events(string) (<-chan *Event, func()) {
+	ch := make(chan *Event)
+	close(ch)
+	return ch, func() {}
+}
+func (sr statusRelay) Journal(*JournalQuery) ([]*JournalEntry, error) {
+	return nil, ErrJournalDisabled
+}
+
+func TestAdminServerReady(t *testing.T) {
+	sts := statusRelay{
+		{Name: "i2b", Subscribed: true, Lag: 5},
+		{Name: "b2i", Subscribed: true},
+	}
+	s := NewAdminServer(&AdminConfig{MaxLag: 60}, sts, nil, log.New())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.Echo().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, get("/health").Code)
+	assert.Equal(t, http.StatusOK, get("/ready").Code)
+
+	sts[0].Lag = 120
+	rec := get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "i2b")
+
+	sts[0].Lag, sts[1].Subscribed = 0, false
+	rec = get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "b2i")
+
+	assert.Equal(t, http.StatusOK, get("/relays/i2b").Code)
+	assert.Equal(t, http.StatusNotFound, get("/relays/x2y").Code)
+}
+
+type eventRelay struct {
+	statusRelay
+	bus *eventBus
+}
+
+func (er eventRelay) Events(name string) (<-chan *Event, func()) { return er.bus.subscribe(name) }
+
+func TestAdminServerEvents(t *testing.T) {
+	er := eventRelay{statusRelay{{Name: "i2b"}}, newEventBus()}
+	srv := httptest.NewServer(NewAdminServer(&AdminConfig{}, er, nil, log.New()).Echo())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events?relay=x2y")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/events?relay=i2b")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	er.bus.publish(&Event{JournalEntry: JournalEntry{Relay: "b2i", Action: JournalSent}})
+	er.bus.publish(&Event{JournalEntry: JournalEntry{Relay: "i2b", Action: JournalDelivered, SeqBegin: 7, SeqEnd: 9}})
+	rd := bufio.NewReader(res.Body)
+	line, _ := rd.ReadString('\n')
+	assert.Equal(t, "event: delivered\n", line)
+	line, _ = rd.ReadString('\n')
+	assert.True(t, strings.HasPrefix(line, "data: {"), line)
+	assert.Contains(t, line, `"seq_begin":7`)
+}
+
+func TestAdminServerControlAuth(t *testing.T) {
+	sts := statusRelay{{Name: "i2b"}}
+	post := func(s *AdminServer, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/relays/i2b/pause", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.Echo().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	s := NewAdminServer(&AdminConfig{Address: "127.0.0.1:9101"}, sts, nil, log.New())
+	assert.Equal(t, http.StatusOK, post(s, ""))
+
+	// reachable from other hosts, without a token
+	s = NewAdminServer(&AdminConfig{Address: "0.0.0.0:9101"}, sts, nil, log.New())
+	assert.Equal(t, http.StatusMethodNotAllowed, post(s, ""))
+
+	s = NewAdminServer(&AdminConfig{Address: "0.0.0.0:9101", Token: "secret"}, sts, nil, log.New())
+	assert.Equal(t, http.StatusUnauthorized, post(s, ""))
+	assert.Equal(t, http.StatusUnauthorized, post(s, "guess"))
+	assert.Equal(t, http.StatusOK, post(s, "secret"))
+}
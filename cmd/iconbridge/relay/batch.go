@@ -0,0 +1,42 @@
+package relay
+
+import (
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+)
+
+const (
+	relayBatchIncrease = 1 // receipts added to the batch limit after each delivered tx
+)
+
+// batchLimit ...
+// adapts the number of receipts packed into a relay message (AIMD):
+// the limit is halved whenever the destination rejects a relay tx for
+// exceeding its block gas limit and grows back slowly after each delivery
+type batchLimit struct {
+	maxReceipts uint64 // zero means no limit
+}
+
+// decrease ...
+// halves the batch size based on the number of receipts in the failed tx
+// and returns the new limit
+func (b *batchLimit) decrease(receipts int) uint64 {
+	limit := uint64(receipts) / 2
+	if b.maxReceipts > 0 && b.maxReceipts/2 < limit {
+		limit = b.maxReceipts / 2
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	b.maxReceipts = limit
+	return limit
+}
+
+func (b *batchLimit) increase() {
+	if b.maxReceipts > 0 {
+		b.maxReceipts += relayBatchIncrease
+	}
+}
+
+func (b *batchLimit) options() chain.SegmentOptions {
+	return chain.SegmentOptions{MaxReceipts: b.maxReceipts}
+}
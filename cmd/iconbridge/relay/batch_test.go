@@ -0,0 +1,33 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchLimit(t *testing.T) {
+	var bl batchLimit
+	assert.Equal(t, uint64(0), bl.options().MaxReceipts)
+
+	// no limit until the first failure
+	bl.increase()
+	assert.Equal(t, uint64(0), bl.options().MaxReceipts)
+
+	assert.Equal(t, uint64(10), bl.decrease(20))
+	assert.Equal(t, uint64(5), bl.decrease(10))
+
+	// a failed tx smaller than the limit still halves the limit
+	bl.increase()
+	assert.Equal(t, uint64(6), bl.options().MaxReceipts)
+	assert.Equal(t, uint64(2), bl.decrease(4))
+
+	// never goes below a single receipt
+	assert.Equal(t, uint64(1), bl.decrease(1))
+	assert.Equal(t, uint64(1), bl.decrease(1))
+
+	for i := 0; i < 4; i++ {
+		bl.increase()
+	}
+	assert.Equal(t, uint64(5), bl.options().MaxReceipts)
+}
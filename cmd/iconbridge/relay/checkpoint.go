@@ -0,0 +1,68 @@
+package relay
+
+import (
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/icon-project/icon-bridge/common/db"
+)
+
+const (
+	// CheckpointBucket maps relay checkpoints from relay name
+	CheckpointBucket db.BucketID = "relay_checkpoint_"
+
+	// defaultCheckpointInterval ...
+	// is how often the checkpoint is saved as the relay receives messages,
+	// a checkpoint behind is safe as the receipts are received again
+	defaultCheckpointInterval = 10 * time.Second
+)
+
+// Checkpoint ...
+// is the progress of a relay persisted across restarts
+type Checkpoint struct {
+	Height   uint64           // last scanned source height
+	Seq      uint64           // last received source event sequence
+	RxSeq    uint64           // last sequence delivered to the destination
+	Receipts []*chain.Receipt // receipts received but not yet delivered
+}
+
+type CheckpointStore interface {
+	// Load ...
+	// returns the last saved checkpoint of the relay "name",
+	// or nil if there is none
+	Load(name string) (*Checkpoint, error)
+	Save(name string, cp *Checkpoint) error
+}
+
+func NewCheckpointStore(database db.Database) (CheckpointStore, error) {
+	bk, err := database.GetBucket(CheckpointBucket)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpointStore{bk: bk}, nil
+}
+
+type checkpointStore struct {
+	bk db.Bucket
+}
+
+func (s *checkpointStore) Load(name string) (*Checkpoint, error) {
+	b, err := s.bk.Get([]byte(name))
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (s *checkpointStore) Save(name string, cp *Checkpoint) error {
+	b, err := codec.RLP.MarshalToBytes(cp)
+	if err != nil {
+		return err
+	}
+	return s.bk.Set([]byte(name), b)
+}
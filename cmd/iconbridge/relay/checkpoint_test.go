@@ -0,0 +1,42 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	cps, err := NewCheckpointStore(db.NewMapDB())
+	require.NoError(t, err)
+
+	cp, err := cps.Load("i2b")
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+
+	saved := &Checkpoint{
+		Height: 120,
+		Seq:    11,
+		RxSeq:  9,
+		Receipts: []*chain.Receipt{{
+			Index:  1,
+			Height: 120,
+			Events: []*chain.Event{
+				{Next: "btp://0x61.bsc/0x034AaDE86BF402F023Aa17E5725fABC4ab9E9798", Sequence: 10, Message: []byte{0x1}},
+				{Next: "btp://0x61.bsc/0x034AaDE86BF402F023Aa17E5725fABC4ab9E9798", Sequence: 11, Message: []byte{0x2}},
+			},
+		}},
+	}
+	require.NoError(t, cps.Save("i2b", saved))
+
+	cp, err = cps.Load("i2b")
+	require.NoError(t, err)
+	assert.Equal(t, saved, cp)
+
+	cp, err = cps.Load("b2i")
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
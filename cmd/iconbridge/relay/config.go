@@ -3,23 +3,104 @@ package relay
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
 	"github.com/icon-project/icon-bridge/common/wallet"
 )
 
 const (
-	DefaultKeyPassword = "gochain"
+	DefaultKeyPassword     = "gochain"
+	defaultShutdownTimeout = time.Minute
 )
 
 type Config struct {
-	Relays []*RelayConfig `json:"relays"`
+	Relays   []*RelayConfig  `json:"relays"`
+	DB       *DBConfig       `json:"db,omitempty"`
+	Election *ElectionConfig `json:"election,omitempty"`
+	Restart  *RestartConfig  `json:"restart,omitempty"`
+	Journal  *JournalConfig  `json:"journal,omitempty"`
+	Costs    *CostsConfig    `json:"costs,omitempty"`
+
+	// ShutdownTimeout
+	// is the time in seconds given to the relays to confirm their in-flight
+	// txs when stopped by a signal, a minute if zero
+	ShutdownTimeout uint `json:"shutdown_timeout,omitempty"`
+}
+
+// ShutdownDeadline ...
+// returns the time given to the relays to drain on shutdown
+func (cfg *Config) ShutdownDeadline() time.Duration {
+	if cfg.ShutdownTimeout == 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(cfg.ShutdownTimeout) * time.Second
+}
+
+// fixedChanges ...
+// returns the sections of "next" that differ from "cfg" but are applied
+// at start only, and so are left unchanged by a reload
+func (cfg *Config) fixedChanges(next *Config) []string {
+	var sections []string
+	if !reflect.DeepEqual(cfg.DB, next.DB) {
+		sections = append(sections, "db")
+	}
+	if !reflect.DeepEqual(cfg.Election, next.Election) {
+		sections = append(sections, "election")
+	}
+	if !reflect.DeepEqual(cfg.Journal, next.Journal) {
+		sections = append(sections, "journal")
+	}
+	if cfg.ShutdownTimeout != next.ShutdownTimeout {
+		sections = append(sections, "shutdown_timeout")
+	}
+	return sections
+}
+
+// DBConfig ...
+// local database used by relays to persist their checkpoints;
+// checkpointing is disabled if it's not configured
+type DBConfig struct {
+	Type string `json:"type"` // badgerdb, goleveldb or mapdb
+	Dir  string `json:"dir"`
+	Name string `json:"name"`
 }
 
 type RelayConfig struct {
 	Name string    `json:"name"`
 	Src  SrcConfig `json:"src"`
 	Dst  DstConfig `json:"dst"`
+
+	// Shadow
+	// runs the relay without sending any tx to the destination, only
+	// logging the relay messages it would have sent
+	Shadow bool `json:"shadow,omitempty"`
+
+	// Rotation
+	// makes the relay submit only during its own turn in the destination
+	// BMC's relayer rotation, unless the active relayer is delayed
+	Rotation *RotationConfig `json:"rotation,omitempty"`
+
+	// Restart
+	// overrides the default restart policy
+	Restart *RestartConfig `json:"restart,omitempty"`
+
+	// Loop
+	// tunes the relay loop timing and batching
+	Loop *LoopConfig `json:"loop,omitempty"`
+
+	// Queue
+	// bounds the receipts pending delivery kept in memory
+	Queue *QueueConfig `json:"queue,omitempty"`
+
+	// Policy
+	// holds or halts the relay on specific BTP messages
+	Policy *PolicyConfig `json:"policy,omitempty"`
+
+	// Runway
+	// forecasts the relay wallet time-to-empty, and stops sending below a floor
+	Runway *RunwayConfig `json:"runway,omitempty"`
 }
 
 type ChainConfig struct {
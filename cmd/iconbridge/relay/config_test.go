@@ -0,0 +1,20 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFixedChanges(t *testing.T) {
+	cfg := &Config{DB: &DBConfig{Type: "goleveldb", Dir: "/data"}, Journal: &JournalConfig{}}
+	next := &Config{
+		Relays:  []*RelayConfig{{Name: "i2b"}},
+		DB:      &DBConfig{Type: "goleveldb", Dir: "/data"},
+		Journal: &JournalConfig{},
+	}
+	assert.Empty(t, cfg.fixedChanges(next))
+
+	next.DB.Dir, next.Election, next.ShutdownTimeout = "/other", &ElectionConfig{}, 10
+	assert.Equal(t, []string{"db", "election", "shutdown_timeout"}, cfg.fixedChanges(next))
+}
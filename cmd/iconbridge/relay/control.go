@@ -0,0 +1,230 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	RelayStateRunning     = "running"
+	RelayStatePaused      = "paused"      // scanning the source, but not sending
+	RelayStateLowBalance  = "low_balance" // not sending until the wallet is topped up above the floor
+	RelayStateDraining    = "draining"    // finishing the in-flight tx before stopping
+	RelayStateQuarantined = "quarantined" // failing too often, waiting before a restart
+	RelayStateStopped     = "stopped"
+)
+
+var (
+	ErrRelayNotFound   = errors.New("relay not found")
+	ErrRelayNotRunning = errors.New("relay not running")
+	ErrJournalDisabled = errors.New("journal disabled: relay db not configured")
+
+	errRelayDrained = errors.New("relay drained")
+)
+
+// relayControl ...
+// is the runtime state of a single relay that an operator can pause,
+// resume, restart or drain without affecting the other relays
+type relayControl struct {
+	mtx         sync.Mutex
+	active      bool // the relay is running or waiting to be restarted
+	paused      bool
+	shadow      bool
+	standby     bool // another instance holds the relay's lease
+	draining    bool
+	restart     bool
+	quarantined bool
+	cancel      context.CancelFunc // aborts the current run
+	kill        context.CancelFunc // stops the relay for good
+	drainCh     chan struct{}      // closed when the relay is asked to drain
+	doneCh      chan struct{}      // closed when the relay becomes inactive
+	wakeCh      chan struct{}      // ends the wait before restarting the relay
+}
+
+func (c *relayControl) state() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	switch {
+	case !c.active:
+		return RelayStateStopped
+	case c.draining:
+		return RelayStateDraining
+	case c.quarantined:
+		return RelayStateQuarantined
+	case c.paused:
+		return RelayStatePaused
+	default:
+		return RelayStateRunning
+	}
+}
+
+func (c *relayControl) isShadow() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.shadow || c.standby
+}
+
+func (c *relayControl) isStandby() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.standby
+}
+
+// setStandby ...
+// returns whether the standby state changed
+func (c *relayControl) setStandby(standby bool) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	changed := c.standby != standby
+	c.standby = standby
+	return changed
+}
+
+func (c *relayControl) setShadow(shadow bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.shadow = shadow
+}
+
+func (c *relayControl) isPaused() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.paused
+}
+
+// activate ...
+// returns false if the relay is already active, "kill" must stop it
+func (c *relayControl) activate(kill context.CancelFunc) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.active {
+		return false
+	}
+	c.active, c.draining = true, false
+	c.kill = kill
+	c.drainCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+	c.wakeCh = make(chan struct{}, 1)
+	return true
+}
+
+func (c *relayControl) deactivate() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.active, c.draining, c.quarantined = false, false, false
+	c.kill = nil
+	close(c.doneCh)
+}
+
+// stop ...
+// aborts the relay without restarting it, and waits until it's inactive
+func (c *relayControl) stop() {
+	c.mtx.Lock()
+	if !c.active {
+		c.mtx.Unlock()
+		return
+	}
+	c.kill()
+	doneCh := c.doneCh
+	c.mtx.Unlock()
+	<-doneCh
+}
+
+func (c *relayControl) started(cancel context.CancelFunc) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.cancel, c.restart = cancel, false
+}
+
+// stopped ...
+// returns whether the run was aborted by a restart request
+func (c *relayControl) stopped() (restart bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.cancel = nil
+	return c.restart
+}
+
+func (c *relayControl) drained() <-chan struct{} {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.drainCh
+}
+
+// done ...
+// returns a channel closed once the relay is inactive
+func (c *relayControl) done() <-chan struct{} {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.active {
+		doneCh := make(chan struct{})
+		close(doneCh)
+		return doneCh
+	}
+	return c.doneCh
+}
+
+func (c *relayControl) woken() <-chan struct{} {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.wakeCh
+}
+
+// wake ...
+// restarts the relay right away if it's waiting to be restarted
+func (c *relayControl) wake() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.active {
+		select {
+		case c.wakeCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *relayControl) setQuarantined(quarantined bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.quarantined = quarantined
+}
+
+func (c *relayControl) pause(paused bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.paused = paused
+}
+
+// requestRestart ...
+// aborts the current run, returns false if the relay isn't active
+func (c *relayControl) requestRestart() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.active {
+		return false
+	}
+	c.restart = true
+	if c.cancel != nil {
+		c.cancel()
+	} else {
+		select {
+		case c.wakeCh <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+func (c *relayControl) drain() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.active {
+		return ErrRelayNotRunning
+	}
+	if !c.draining {
+		c.draining = true
+		close(c.drainCh)
+	}
+	return nil
+}
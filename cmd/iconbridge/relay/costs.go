@@ -0,0 +1,129 @@
+package relay
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+// CostsConfig ...
+// converts the fees paid for relay txs into a common unit in cost reports
+type CostsConfig struct {
+	Unit   string                `json:"unit"`   // e.g. "USD"
+	Prices map[string]*CoinPrice `json:"prices"` // by relay name, or by destination chain name, e.g. "bsc"
+}
+
+// CoinPrice ...
+// is the price of the native coin of a destination chain
+type CoinPrice struct {
+	Symbol   string  `json:"symbol"`
+	Decimals uint    `json:"decimals"`
+	Price    float64 `json:"price"` // of a whole coin, in CostsConfig.Unit
+}
+
+// CostRow ...
+// aggregates the fees paid for the relay txs of a relay, on a day or overall
+type CostRow struct {
+	Relay         string   `json:"relay"`
+	Day           string   `json:"day,omitempty"` // UTC, e.g. "2022-06-01"
+	Since         int64    `json:"since"`         // unix nano, of the oldest tx aggregated
+	Txs           int      `json:"txs"`
+	Failed        int      `json:"failed"`   // txs paid for without delivering their messages, rolled back ones included
+	Messages      uint64   `json:"messages"` // delivered
+	GasUsed       uint64   `json:"gas_used"`
+	Fee           *big.Int `json:"fee"` // in the smallest unit of the destination coin
+	FeePerMessage *big.Int `json:"fee_per_message"`
+	Symbol        string   `json:"symbol,omitempty"`
+	Coins         float64  `json:"coins,omitempty"`       // Fee in whole coins, if priced
+	Value         float64  `json:"value,omitempty"`       // Fee in the unit of the report, if priced
+	PerMessage    float64  `json:"per_message,omitempty"` // Value per delivered message
+}
+
+// CostReport ...
+// is the cost of relaying, from the fees recorded in the journal; the
+// journal keeps the latest "JournalLimit" entries of each relay, so the
+// costs of a relay are reported since its oldest entry kept, see CostRow.Since
+type CostReport struct {
+	Unit         string     `json:"unit,omitempty"`
+	JournalLimit uint64     `json:"journal_limit,omitempty"` // entries kept per relay
+	Rows         []*CostRow `json:"rows"`
+}
+
+// NewCostReport ...
+// aggregates the fees of the delivered, failed and rolled back relay txs in
+// "entries" by relay, and by day if "daily"; "chains" maps relay names to
+// their destination chain names, to find their prices in "cfg"; failed and
+// rolled back txs without a receipt aren't counted, they may not have been
+// included
+func NewCostReport(entries []*JournalEntry, daily bool, cfg *CostsConfig, chains map[string]string) *CostReport {
+	rep := &CostReport{}
+	if cfg != nil {
+		rep.Unit = cfg.Unit
+	}
+	rows := make(map[[2]string]*CostRow)
+	for _, e := range entries {
+		if e.Action != JournalDelivered && e.Action != JournalFailed && e.Action != JournalRollback {
+			continue
+		}
+		fee, _ := new(big.Int).SetString(e.Fee, 10)
+		if e.Action != JournalDelivered && (fee == nil || fee.Sign() == 0) && e.GasUsed == 0 {
+			continue
+		}
+		key := [2]string{e.Relay, ""}
+		if daily {
+			key[1] = time.Unix(0, e.Time).UTC().Format("2006-01-02")
+		}
+		row, ok := rows[key]
+		if !ok {
+			row = &CostRow{Relay: key[0], Day: key[1], Since: e.Time, Fee: new(big.Int)}
+			rows[key] = row
+			rep.Rows = append(rep.Rows, row)
+		}
+		if e.Time < row.Since {
+			row.Since = e.Time
+		}
+		row.Txs++
+		if e.Action != JournalDelivered {
+			row.Failed++
+		} else if e.SeqEnd >= e.SeqBegin && e.SeqBegin > 0 {
+			row.Messages += e.SeqEnd - e.SeqBegin + 1
+		}
+		row.GasUsed += e.GasUsed
+		if fee != nil {
+			row.Fee.Add(row.Fee, fee)
+		}
+	}
+	sort.SliceStable(rep.Rows, func(i, j int) bool {
+		if rep.Rows[i].Relay != rep.Rows[j].Relay {
+			return rep.Rows[i].Relay < rep.Rows[j].Relay
+		}
+		return rep.Rows[i].Day < rep.Rows[j].Day
+	})
+	for _, row := range rep.Rows {
+		row.FeePerMessage = new(big.Int)
+		if row.Messages > 0 {
+			row.FeePerMessage.Div(row.Fee, new(big.Int).SetUint64(row.Messages))
+		}
+		if p := cfg.price(row.Relay, chains[row.Relay]); p != nil {
+			row.Symbol = p.Symbol
+			coins := new(big.Float).Quo(new(big.Float).SetInt(row.Fee),
+				new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.Decimals)), nil)))
+			row.Coins, _ = coins.Float64()
+			row.Value = row.Coins * p.Price
+			if row.Messages > 0 {
+				row.PerMessage = row.Value / float64(row.Messages)
+			}
+		}
+	}
+	return rep
+}
+
+func (cfg *CostsConfig) price(relay, chain string) *CoinPrice {
+	if cfg == nil {
+		return nil
+	}
+	if p, ok := cfg.Prices[relay]; ok {
+		return p
+	}
+	return cfg.Prices[chain]
+}
@@ -0,0 +1,52 @@
+package relay
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostReport(t *testing.T) {
+	day := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*JournalEntry{
+		{Relay: "i2b", Time: day.UnixNano(), Action: JournalSent, SeqBegin: 1, SeqEnd: 4},
+		{Relay: "i2b", Time: day.UnixNano(), Action: JournalDelivered, SeqBegin: 1, SeqEnd: 4, GasUsed: 100, Fee: "2000000000000000000"},
+		{Relay: "i2b", Time: day.Add(24 * time.Hour).UnixNano(), Action: JournalFailed, SeqBegin: 5, SeqEnd: 5, GasUsed: 10, Fee: "1000000000000000000"},
+		{Relay: "i2b", Time: day.Add(24 * time.Hour).UnixNano(), Action: JournalDelivered, SeqBegin: 5, SeqEnd: 5, GasUsed: 20, Fee: "1000000000000000000"},
+		{Relay: "i2b", Time: day.Add(24 * time.Hour).UnixNano(), Action: JournalFailed, SeqBegin: 6, SeqEnd: 6, Error: "receipt not found"},
+		{Relay: "i2b", Time: day.Add(24 * time.Hour).UnixNano(), Action: JournalRollback, SeqBegin: 6, SeqEnd: 6, GasUsed: 10, Fee: "1000000000000000000"},
+		{Relay: "i2b", Time: day.Add(24 * time.Hour).UnixNano(), Action: JournalRollback, SeqBegin: 7, SeqEnd: 7, Error: "tx failed"},
+		{Relay: "b2i", Time: day.UnixNano(), Action: JournalDelivered, SeqBegin: 1, SeqEnd: 1, GasUsed: 5, Fee: "50"},
+	}
+	cfg := &CostsConfig{Unit: "USD", Prices: map[string]*CoinPrice{"bsc": {Symbol: "BNB", Decimals: 18, Price: 300}}}
+	chains := map[string]string{"i2b": "bsc", "b2i": "icon"}
+
+	rep := NewCostReport(entries, false, cfg, chains)
+	require.Len(t, rep.Rows, 2)
+	assert.Equal(t, "USD", rep.Unit)
+	b2i, i2b := rep.Rows[0], rep.Rows[1]
+	assert.Equal(t, "b2i", b2i.Relay)
+	assert.Equal(t, big.NewInt(50), b2i.Fee)
+	assert.Zero(t, b2i.Value)
+
+	assert.Equal(t, day.UnixNano(), i2b.Since)
+	assert.Equal(t, 4, i2b.Txs, "failed and rolled back txs without a receipt aren't counted")
+	assert.Equal(t, 2, i2b.Failed)
+	assert.Equal(t, uint64(5), i2b.Messages)
+	assert.Equal(t, uint64(140), i2b.GasUsed)
+	assert.Equal(t, "5000000000000000000", i2b.Fee.String())
+	assert.Equal(t, "1000000000000000000", i2b.FeePerMessage.String())
+	assert.InDelta(t, 5.0, i2b.Coins, 1e-9)
+	assert.InDelta(t, 1500.0, i2b.Value, 1e-6)
+	assert.InDelta(t, 300.0, i2b.PerMessage, 1e-6)
+
+	rep = NewCostReport(entries, true, nil, chains)
+	require.Len(t, rep.Rows, 3)
+	assert.Equal(t, "2022-06-01", rep.Rows[1].Day)
+	assert.Equal(t, "2022-06-02", rep.Rows[2].Day)
+	assert.Equal(t, 3, rep.Rows[2].Txs)
+	assert.Equal(t, day.Add(24*time.Hour).UnixNano(), rep.Rows[2].Since)
+}
@@ -0,0 +1,257 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/icon-project/icon-bridge/common/db"
+)
+
+const (
+	// LeaseBucket maps relay leases from relay name
+	LeaseBucket db.BucketID = "relay_lease_"
+
+	defaultLeaseTTL       = 15 * time.Second
+	fileLeaseLockRetry    = 20
+	fileLeaseLockInterval = 50 * time.Millisecond
+)
+
+// ElectionConfig ...
+// lets instances sharing the same backend elect a single active relayer
+// per relay name, the others run on standby in shadow mode. Only the "file"
+// backend elects across processes, e.g. on a shared volume: the relay db
+// of the "db" backend is opened exclusively (goleveldb and badger lock it),
+// so it can't be shared by instances, and it's refused unless "SingleNode"
+// says so
+type ElectionConfig struct {
+	Type       string `json:"type"`        // "file" or "db"
+	Dir        string `json:"dir"`         // shared directory of the "file" backend
+	ID         string `json:"id"`          // identifies this instance, defaults to "hostname:pid"
+	LeaseTTL   uint   `json:"lease_ttl"`   // seconds before a lease that isn't renewed expires
+	SingleNode bool   `json:"single_node"` // accepts the "db" backend, which elects within this process only, e.g. in tests
+}
+
+func (cfg *ElectionConfig) leaseTTL() time.Duration {
+	if cfg.LeaseTTL == 0 {
+		return defaultLeaseTTL
+	}
+	return time.Duration(cfg.LeaseTTL) * time.Second
+}
+
+// Elector ...
+// grants a renewable lease per relay name to a single instance
+type Elector interface {
+	// Acquire ...
+	// acquires or renews the lease of "name" for "ttl",
+	// and returns whether this instance holds it
+	Acquire(name string, ttl time.Duration) (bool, error)
+	// Release ...
+	// gives up the lease of "name" if this instance holds it
+	Release(name string) error
+}
+
+// NewElectorFunc ...
+// "database" is the relay database, nil if it's not configured
+type NewElectorFunc func(cfg *ElectionConfig, id string, database db.Database) (Elector, error)
+
+var Electors = map[string]NewElectorFunc{
+	"file": newFileElector,
+	"db":   newDBElector,
+}
+
+func NewElector(cfg *ElectionConfig, database db.Database) (Elector, error) {
+	id := cfg.ID
+	if id == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		id = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+	newElector, ok := Electors[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported election type: %s", cfg.Type)
+	}
+	return newElector(cfg, id, database)
+}
+
+type lease struct {
+	Owner  string `json:"owner"`
+	Expiry int64  `json:"expiry"` // unix nano
+}
+
+// grant ...
+// returns whether "id" may take the lease "l", which may be nil
+func (l *lease) grant(id string, now time.Time) bool {
+	return l == nil || l.Owner == id || now.UnixNano() >= l.Expiry
+}
+
+// fileElector ...
+// keeps a lease file per relay name in a directory shared by all
+// instances, updates are serialized with an exclusively created lock file
+type fileElector struct {
+	dir string
+	id  string
+}
+
+func newFileElector(cfg *ElectionConfig, id string, database db.Database) (Elector, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("election dir is required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fileElector{dir: cfg.Dir, id: id}, nil
+}
+
+func (e *fileElector) path(name string) string {
+	return filepath.Join(e.dir, url.PathEscape(name)+".lease")
+}
+
+// lock ...
+// creates the lock file of "name", removing it first if it was left
+// behind for longer than "ttl" by a crashed instance
+func (e *fileElector) lock(name string, ttl time.Duration) (unlock func(), err error) {
+	path := e.path(name) + ".lock"
+	for i := 0; i < fileLeaseLockRetry; i++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		} else if !os.IsExist(err) {
+			return nil, err
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > ttl {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(fileLeaseLockInterval)
+	}
+	return nil, fmt.Errorf("lease locked: %s", path)
+}
+
+func (e *fileElector) read(name string) (*lease, error) {
+	b, err := ioutil.ReadFile(e.path(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	l := &lease{}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (e *fileElector) Acquire(name string, ttl time.Duration) (bool, error) {
+	unlock, err := e.lock(name, ttl)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	l, err := e.read(name)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if !l.grant(e.id, now) {
+		return false, nil
+	}
+	b, err := json.Marshal(&lease{Owner: e.id, Expiry: now.Add(ttl).UnixNano()})
+	if err != nil {
+		return false, err
+	}
+	tmp := e.path(name) + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, e.path(name)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (e *fileElector) Release(name string) error {
+	unlock, err := e.lock(name, defaultLeaseTTL)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l, err := e.read(name)
+	if err != nil || l == nil || l.Owner != e.id {
+		return err
+	}
+	return os.Remove(e.path(name))
+}
+
+// dbElector ...
+// keeps the leases in the relay database; since a database is opened by a
+// single process, it only elects between the multiRelays of that process
+type dbElector struct {
+	mtx sync.Mutex
+	bk  db.Bucket
+	id  string
+}
+
+func newDBElector(cfg *ElectionConfig, id string, database db.Database) (Elector, error) {
+	if !cfg.SingleNode {
+		return nil, fmt.Errorf("election type db only elects within a single process, " +
+			"use type file with a dir on storage shared by the instances, or set single_node")
+	}
+	if database == nil {
+		return nil, fmt.Errorf("election type db requires the relay db")
+	}
+	bk, err := database.GetBucket(LeaseBucket)
+	if err != nil {
+		return nil, err
+	}
+	return &dbElector{bk: bk, id: id}, nil
+}
+
+func (e *dbElector) read(name string) (*lease, error) {
+	b, err := e.bk.Get([]byte(name))
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	l := &lease{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (e *dbElector) Acquire(name string, ttl time.Duration) (bool, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	l, err := e.read(name)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if !l.grant(e.id, now) {
+		return false, nil
+	}
+	b, err := codec.RLP.MarshalToBytes(&lease{Owner: e.id, Expiry: now.Add(ttl).UnixNano()})
+	if err != nil {
+		return false, err
+	}
+	return true, e.bk.Set([]byte(name), b)
+}
+
+func (e *dbElector) Release(name string) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	l, err := e.read(name)
+	if err != nil || l == nil || l.Owner != e.id {
+		return err
+	}
+	return e.bk.Delete([]byte(name))
+}
@@ -0,0 +1,106 @@
+package relay
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testElector(t *testing.T, a, b Elector) {
+	ok, err := a.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// renewed by the holder, refused to the other
+	ok, err = a.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = b.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// leases are per relay name
+	ok, err = b.Acquire("b2i", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// only the holder releases the lease
+	require.NoError(t, b.Release("i2b"))
+	ok, err = b.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, a.Release("i2b"))
+	ok, err = b.Acquire("i2b", time.Nanosecond)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// expired leases are taken over
+	time.Sleep(time.Millisecond)
+	ok, err = a.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestFileElector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "election")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := &ElectionConfig{Type: "file", Dir: dir}
+	a, err := newFileElector(cfg, "a", nil)
+	require.NoError(t, err)
+	b, err := newFileElector(cfg, "b", nil)
+	require.NoError(t, err)
+	testElector(t, a, b)
+}
+
+func TestDBElector(t *testing.T) {
+	database := db.NewMapDB()
+	_, err := newDBElector(&ElectionConfig{Type: "db"}, "a", database)
+	assert.Error(t, err, "db elector refused unless single node")
+
+	cfg := &ElectionConfig{Type: "db", SingleNode: true}
+	a, err := newDBElector(cfg, "a", database)
+	require.NoError(t, err)
+	b, err := newDBElector(cfg, "b", database)
+	require.NoError(t, err)
+	testElector(t, a, b)
+}
+
+func TestElect(t *testing.T) {
+	database := db.NewMapDB()
+	cfg := &ElectionConfig{Type: "db", SingleNode: true}
+	a, err := newDBElector(cfg, "a", database)
+	require.NoError(t, err)
+	b, err := newDBElector(cfg, "b", database)
+	require.NoError(t, err)
+	r, err := newRelay(&RelayConfig{Name: "i2b"}, nil, &segmentSender{}, nil, log.New())
+	require.NoError(t, err)
+	r.ctl.standby = true
+	mr := &multiRelay{log: log.New(), relays: []*relay{r}, elector: a, leaseTTL: time.Minute}
+	require.True(t, r.ctl.activate(func() {}))
+
+	// no lease until the relay is subscribed
+	mr.elect()
+	assert.True(t, r.ctl.isStandby())
+	r.state.setSubscribed(true)
+	mr.elect()
+	assert.False(t, r.ctl.isStandby())
+	ok, err := b.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// a quarantined relay gives its lease up
+	r.ctl.setQuarantined(true)
+	mr.elect()
+	assert.True(t, r.ctl.isStandby())
+	ok, err = b.Acquire("i2b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,94 @@
+package relay
+
+import (
+	"sync"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+)
+
+const (
+	defaultEventBufferSize = 256
+)
+
+// Event types, in addition to the journal actions
+const (
+	EventReceipts  = "receipts"  // receipts with events received from the source
+	EventLink      = "link"      // link status of the destination changed
+	EventRestarted = "restarted" // relay restarted after a failure or on request
+)
+
+// Event ...
+// is a relay lifecycle event pushed to the admin event stream;
+// Action is a journal action or an event type
+type Event struct {
+	JournalEntry
+	Link *chain.BMCLinkStatus `json:"link,omitempty"`
+}
+
+// eventBus ...
+// broadcasts relay events to the subscribers of the admin event stream,
+// a subscriber falling behind is dropped rather than blocking the relays
+type eventBus struct {
+	mtx  sync.Mutex
+	subs map[chan *Event]string // relay name, all relays if empty
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[chan *Event]string)}
+}
+
+// subscribe ...
+// returns the events of relay "name", or of all relays if empty, on a
+// channel closed when "cancel" is called or the subscriber falls behind
+func (b *eventBus) subscribe(name string) (events <-chan *Event, cancel func()) {
+	ch := make(chan *Event, defaultEventBufferSize)
+	b.mtx.Lock()
+	b.subs[ch] = name
+	b.mtx.Unlock()
+	return ch, func() {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// listening ...
+// returns whether any stream is subscribed
+func (b *eventBus) listening() bool {
+	if b == nil {
+		return false
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.subs) > 0
+}
+
+func (b *eventBus) publish(e *Event) {
+	if b == nil {
+		return
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for ch, name := range b.subs {
+		if name != "" && name != e.Relay {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// event ...
+// publishes the relay event "e" of type "action"
+func (r *relay) event(action string, e *Event) {
+	e.Relay, e.Time, e.Action = r.cfg.Name, time.Now().UnixNano(), action
+	r.ev.publish(e)
+}
@@ -0,0 +1,39 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus(t *testing.T) {
+	b := newEventBus()
+	all, cancelAll := b.subscribe("")
+	i2b, cancelI2b := b.subscribe("i2b")
+	assert.True(t, b.listening())
+
+	b.publish(&Event{JournalEntry: JournalEntry{Relay: "i2b", Action: JournalSent}})
+	b.publish(&Event{JournalEntry: JournalEntry{Relay: "b2i", Action: EventRestarted}})
+
+	assert.Equal(t, JournalSent, (<-all).Action)
+	assert.Equal(t, EventRestarted, (<-all).Action)
+	assert.Equal(t, JournalSent, (<-i2b).Action)
+	assert.Len(t, i2b, 0)
+
+	// a subscriber falling behind is dropped
+	for i := 0; i <= defaultEventBufferSize; i++ {
+		b.publish(&Event{JournalEntry: JournalEntry{Relay: "b2i"}})
+	}
+	for range all {
+	}
+	assert.True(t, b.listening())
+	cancelAll()
+	cancelI2b()
+	_, ok := <-i2b
+	assert.False(t, ok)
+	assert.False(t, b.listening())
+
+	var nb *eventBus
+	nb.publish(&Event{})
+	assert.False(t, nb.listening())
+}
@@ -0,0 +1,334 @@
+package relay
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/btp"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+const (
+	// JournalBucket maps the journal of a relay from relay name,
+	// and its entries from relay name and entry index
+	JournalBucket db.BucketID = "relay_journal_"
+
+	defaultJournalMaxEntries = 100000
+	defaultJournalQueryLimit = 100
+)
+
+// Journal actions
+const (
+	JournalSegment   = "segment"    // relay tx created for a sequence range
+	JournalSent      = "sent"       // relay tx accepted by the destination
+	JournalSendRetry = "send_retry" // relay tx rejected, sending again
+	JournalDelivered = "delivered"  // relay tx included at Height
+	JournalFailed    = "failed"     // relay tx reverted or its receipt not found
+	JournalRollback  = "rollback"   // pipelined relay tx abandoned after an earlier one failed
+	JournalHeld      = "held"       // message held back by the relay policy
+	JournalReleased  = "released"   // held message released by an operator
+)
+
+// JournalConfig ...
+// bounds the journal kept per relay in the relay db
+type JournalConfig struct {
+	MaxEntries uint `json:"max_entries"` // oldest entries are dropped beyond it
+}
+
+// JournalEntry ...
+// is a relay action recorded in the journal
+type JournalEntry struct {
+	Relay    string   `json:"relay"`
+	Time     int64    `json:"time"` // unix nano
+	Action   string   `json:"action"`
+	TxID     string   `json:"tx_id,omitempty"`
+	SeqBegin uint64   `json:"seq_begin,omitempty"`
+	SeqEnd   uint64   `json:"seq_end,omitempty"`
+	Receipts int      `json:"receipts,omitempty"`
+	Size     int      `json:"size,omitempty"` // relay message bytes
+	Retry    int      `json:"retry,omitempty"`
+	Height   uint64   `json:"height,omitempty"` // destination block including the tx
+	Error    string   `json:"error,omitempty"`
+	Messages []string `json:"messages,omitempty"` // decoded BTP messages of a segment entry
+	GasUsed  uint64   `json:"gas_used,omitempty"`
+	Fee      string   `json:"fee,omitempty"` // paid for the tx, in the smallest unit of the destination coin
+}
+
+// JournalQuery ...
+// selects the journal entries of a relay; zero values match any entry
+type JournalQuery struct {
+	Relay string
+	Seq   uint64 // within [SeqBegin, SeqEnd]
+	From  time.Time
+	To    time.Time
+	TxID  string
+	Limit int // latest entries returned, defaultJournalQueryLimit if zero
+}
+
+func (q *JournalQuery) match(e *JournalEntry) bool {
+	switch {
+	case q.Seq != 0 && (q.Seq < e.SeqBegin || q.Seq > e.SeqEnd):
+		return false
+	case !q.From.IsZero() && e.Time < q.From.UnixNano():
+		return false
+	case !q.To.IsZero() && e.Time > q.To.UnixNano():
+		return false
+	case q.TxID != "" && !strings.EqualFold(q.TxID, e.TxID):
+		return false
+	}
+	return true
+}
+
+// ParseJournalQuery ...
+// parses the url query parameters made by JournalQuery.Values
+func ParseJournalQuery(v url.Values) (*JournalQuery, error) {
+	q := &JournalQuery{Relay: v.Get("relay"), TxID: v.Get("tx")}
+	var err error
+	if s := v.Get("seq"); s != "" {
+		if q.Seq, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid seq: %v", err)
+		}
+	}
+	if s := v.Get("from"); s != "" {
+		if q.From, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, fmt.Errorf("invalid from: %v", err)
+		}
+	}
+	if s := v.Get("to"); s != "" {
+		if q.To, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, fmt.Errorf("invalid to: %v", err)
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("invalid limit: %v", err)
+		}
+	}
+	return q, nil
+}
+
+// Values ...
+// returns the url query parameters of "q"
+func (q *JournalQuery) Values() url.Values {
+	v := url.Values{}
+	v.Set("relay", q.Relay)
+	if q.Seq != 0 {
+		v.Set("seq", strconv.FormatUint(q.Seq, 10))
+	}
+	if !q.From.IsZero() {
+		v.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		v.Set("to", q.To.Format(time.RFC3339))
+	}
+	if q.TxID != "" {
+		v.Set("tx", q.TxID)
+	}
+	if q.Limit != 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return v
+}
+
+type JournalStore interface {
+	Append(e *JournalEntry) error
+	// Query ...
+	// returns the latest entries matching "q", oldest first
+	Query(q *JournalQuery) ([]*JournalEntry, error)
+}
+
+func NewJournalStore(database db.Database, cfg *JournalConfig) (JournalStore, error) {
+	bk, err := database.GetBucket(JournalBucket)
+	if err != nil {
+		return nil, err
+	}
+	return &journalStore{bk: bk, maxEntries: cfg.Limit()}, nil
+}
+
+// Limit ...
+// returns the number of entries kept per relay
+func (cfg *JournalConfig) Limit() uint64 {
+	if cfg == nil || cfg.MaxEntries == 0 {
+		return defaultJournalMaxEntries
+	}
+	return uint64(cfg.MaxEntries)
+}
+
+type journalMeta struct {
+	First uint64 // index of the oldest entry
+	Next  uint64 // index of the next entry
+}
+
+// journalStore ...
+// keeps the entries of each relay in an append-only log indexed from
+// First to Next, trimmed to maxEntries
+type journalStore struct {
+	mtx        sync.Mutex
+	bk         db.Bucket
+	maxEntries uint64
+}
+
+func (js *journalStore) key(name string, index uint64) []byte {
+	k := make([]byte, len(name)+9)
+	copy(k, name)
+	k[len(name)] = '/'
+	binary.BigEndian.PutUint64(k[len(name)+1:], index)
+	return k
+}
+
+func (js *journalStore) meta(name string) (*journalMeta, error) {
+	m := &journalMeta{}
+	b, err := js.bk.Get([]byte(name))
+	if err != nil || len(b) == 0 {
+		return m, err
+	}
+	_, err = codec.RLP.UnmarshalFromBytes(b, m)
+	return m, err
+}
+
+func (js *journalStore) Append(e *JournalEntry) error {
+	js.mtx.Lock()
+	defer js.mtx.Unlock()
+	m, err := js.meta(e.Relay)
+	if err != nil {
+		return err
+	}
+	b, err := codec.RLP.MarshalToBytes(e)
+	if err != nil {
+		return err
+	}
+	if err := js.bk.Set(js.key(e.Relay, m.Next), b); err != nil {
+		return err
+	}
+	m.Next++
+	for ; m.Next-m.First > js.maxEntries; m.First++ {
+		if err := js.bk.Delete(js.key(e.Relay, m.First)); err != nil {
+			return err
+		}
+	}
+	if b, err = codec.RLP.MarshalToBytes(m); err != nil {
+		return err
+	}
+	return js.bk.Set([]byte(e.Relay), b)
+}
+
+func (js *journalStore) Query(q *JournalQuery) ([]*JournalEntry, error) {
+	js.mtx.Lock()
+	defer js.mtx.Unlock()
+	m, err := js.meta(q.Relay)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultJournalQueryLimit
+	}
+	var entries []*JournalEntry
+	for i := m.Next; i > m.First && len(entries) < limit; i-- {
+		b, err := js.bk.Get(js.key(q.Relay, i-1))
+		if err != nil {
+			return nil, err
+		}
+		e := &JournalEntry{}
+		if _, err := codec.RLP.UnmarshalFromBytes(b, e); err != nil {
+			return nil, err
+		}
+		if !q.From.IsZero() && e.Time < q.From.UnixNano() {
+			break // entries are appended in time order
+		}
+		if q.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// journal ...
+// records the relay action "e", if the journal is enabled, and publishes it
+// to the event stream
+func (r *relay) journal(action string, e JournalEntry) {
+	e.Relay, e.Time, e.Action = r.cfg.Name, time.Now().UnixNano(), action
+	r.ev.publish(&Event{JournalEntry: e})
+	if r.js == nil {
+		return
+	}
+	if err := r.js.Append(&e); err != nil {
+		r.log.WithFields(log.Fields{"action": action, "error": err}).Warn("failed to append journal entry")
+	}
+}
+
+// txJournalEntry ...
+// returns the journal entry of the relay tx "itx"
+func txJournalEntry(itx *inflightTx) JournalEntry {
+	e := JournalEntry{
+		SeqBegin: itx.seqBegin,
+		SeqEnd:   itx.seqEnd,
+		Receipts: itx.receipts,
+		Size:     itx.tx.Size(),
+	}
+	if id := itx.tx.ID(); id != nil {
+		e.TxID = fmt.Sprint(id)
+	}
+	return e
+}
+
+// withCost ...
+// adds the cost of the relay tx "itx" to "e", if its sender reports it
+func (r *relay) withCost(e JournalEntry, itx *inflightTx) JournalEntry {
+	ctx, ok := itx.tx.(chain.CostedRelayTx)
+	if !ok {
+		return e
+	}
+	if cost := ctx.Cost(); cost != nil && cost.Fee != nil {
+		e.GasUsed, e.Fee = cost.GasUsed, cost.Fee.String()
+		relayMetrics.txFees.add(r.cfg.Name, bigToFloat(cost.Fee))
+		r.rw.spent(cost.Fee)
+	}
+	return e
+}
+
+// btpMessages ...
+// returns the summaries of the BTP messages of "receipts"
+func btpMessages(receipts []*chain.Receipt) []string {
+	var msgs []string
+	for _, receipt := range receipts {
+		for _, event := range receipt.Events {
+			msgs = append(msgs, btpMessage(event))
+		}
+	}
+	return msgs
+}
+
+func btpMessage(event *chain.Event) string {
+	d, err := btp.Decode(event.Message)
+	if err != nil {
+		return fmt.Sprintf("seq=%d %v", event.Sequence, err)
+	}
+	return d.String()
+}
+
+// sequenceRange ...
+// returns the first and last event sequences of "receipts"
+func sequenceRange(receipts []*chain.Receipt) (begin, end uint64) {
+	for _, receipt := range receipts {
+		if len(receipt.Events) == 0 {
+			continue
+		}
+		if begin == 0 {
+			begin = receipt.Events[0].Sequence
+		}
+		end = receipt.Events[len(receipt.Events)-1].Sequence
+	}
+	return begin, end
+}
@@ -0,0 +1,146 @@
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalStore(t *testing.T) {
+	js, err := NewJournalStore(db.NewMapDB(), &JournalConfig{MaxEntries: 4})
+	require.NoError(t, err)
+
+	at := time.Unix(1000, 0)
+	for i := uint64(0); i < 6; i++ {
+		require.NoError(t, js.Append(&JournalEntry{
+			Relay:    "i2b",
+			Time:     at.Add(time.Duration(i) * time.Second).UnixNano(),
+			Action:   JournalDelivered,
+			TxID:     []string{"0xAA", "0xBB"}[i%2],
+			SeqBegin: 10*i + 1,
+			SeqEnd:   10*i + 10,
+		}))
+	}
+	require.NoError(t, js.Append(&JournalEntry{Relay: "b2i", Action: JournalSent}))
+
+	// the oldest entries are trimmed
+	entries, err := js.Query(&JournalQuery{Relay: "i2b"})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, uint64(21), entries[0].SeqBegin)
+	assert.Equal(t, uint64(51), entries[3].SeqBegin)
+
+	entries, err = js.Query(&JournalQuery{Relay: "i2b", Seq: 45})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "0xAA", entries[0].TxID)
+
+	entries, err = js.Query(&JournalQuery{Relay: "i2b", TxID: "0xaa", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(41), entries[0].SeqBegin)
+
+	entries, err = js.Query(&JournalQuery{Relay: "i2b", From: at.Add(3 * time.Second), To: at.Add(4 * time.Second)})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	q, err := ParseJournalQuery((&JournalQuery{Relay: "i2b", Seq: 45, From: at.UTC(), TxID: "0xBB"}).Values())
+	require.NoError(t, err)
+	assert.Equal(t, &JournalQuery{Relay: "i2b", Seq: 45, From: at.UTC(), TxID: "0xBB"}, q)
+}
+
+func TestMultiRelayJournal(t *testing.T) {
+	js, err := NewJournalStore(db.NewMapDB(), nil)
+	require.NoError(t, err)
+	var relays []*relay
+	for _, name := range []string{"i2b", "b2i"} {
+		r, err := newRelay(&RelayConfig{Name: name}, nil, &segmentSender{}, nil, log.New())
+		require.NoError(t, err)
+		relays = append(relays, r)
+		for seq := uint64(1); seq <= 3; seq++ {
+			require.NoError(t, js.Append(&JournalEntry{Relay: name, Action: JournalSent, SeqBegin: seq, SeqEnd: seq}))
+		}
+	}
+	mr := &multiRelay{log: log.New(), js: js, relays: relays}
+
+	entries, err := mr.Journal(&JournalQuery{Relay: "b2i"})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	// without a relay, the entries of every relay up to the limit
+	entries, err = mr.Journal(&JournalQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "i2b", entries[0].Relay)
+	assert.Equal(t, uint64(2), entries[0].SeqBegin)
+	assert.Equal(t, "b2i", entries[3].Relay)
+	assert.Equal(t, uint64(3), entries[3].SeqBegin)
+}
+
+func TestPipelineJournal(t *testing.T) {
+	js, err := NewJournalStore(db.NewMapDB(), nil)
+	require.NoError(t, err)
+	s := &pipelinedSender{fail: 2}
+	r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{Pipeline: 2}}, nil, s, nil, log.New())
+	require.NoError(t, err)
+	r.js = js
+
+	var receipts []*chain.Receipt
+	for seq := uint64(1); seq <= 3; seq++ {
+		receipts = append(receipts, &chain.Receipt{Height: seq, Events: []*chain.Event{{Sequence: seq}}})
+	}
+	_, err = r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+		func(*chain.Message, uint64, time.Time) {})
+	require.NoError(t, err)
+
+	entries, err := js.Query(&JournalQuery{Relay: "i2b"})
+	require.NoError(t, err)
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	assert.Equal(t, []string{
+		JournalSegment, JournalSent, JournalSegment, JournalSent, JournalDelivered,
+		JournalSegment, JournalSent, JournalFailed, JournalRollback,
+	}, actions)
+	assert.Equal(t, uint64(2), entries[7].SeqBegin)
+	assert.Equal(t, "2", entries[7].TxID)
+	assert.Len(t, entries[0].Messages, 1)
+}
+
+func TestPipelineJournalRollbackCost(t *testing.T) {
+	js, err := NewJournalStore(db.NewMapDB(), nil)
+	require.NoError(t, err)
+	s := &pipelinedSender{fail: 2, costed: true}
+	r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{Pipeline: 3}}, nil, s, nil, log.New())
+	require.NoError(t, err)
+	r.js = js
+
+	var receipts []*chain.Receipt
+	for seq := uint64(1); seq <= 3; seq++ {
+		receipts = append(receipts, &chain.Receipt{Height: seq, Events: []*chain.Event{{Sequence: seq}}})
+	}
+	_, err = r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+		func(*chain.Message, uint64, time.Time) {})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, s.confirmed, "the rolled back tx is confirmed")
+
+	entries, err := js.Query(&JournalQuery{Relay: "i2b"})
+	require.NoError(t, err)
+	rollback := entries[len(entries)-1]
+	assert.Equal(t, JournalRollback, rollback.Action)
+	assert.Equal(t, uint64(3), rollback.SeqBegin)
+	assert.Equal(t, uint64(10), rollback.GasUsed)
+	assert.Equal(t, "20", rollback.Fee)
+	rep := NewCostReport(entries, false, nil, nil)
+	require.Len(t, rep.Rows, 1)
+	assert.Equal(t, 3, rep.Rows[0].Txs)
+	assert.Equal(t, 2, rep.Rows[0].Failed)
+	assert.Equal(t, "60", rep.Rows[0].Fee.String())
+}
@@ -0,0 +1,140 @@
+package relay
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	relayMinTickerInterval = 100 * time.Millisecond
+)
+
+// defaultLoopConfig ...
+// applies to the values left to default by both the relay config and
+// the destination chain, and to relays without a loop config
+var defaultLoopConfig = LoopConfig{
+	TickerInterval:                  5,
+	BalanceCheckInterval:            60,
+	TriggerReceiptsCount:            20,
+	TxSendWaitInterval:              0.5,
+	TxReceiptWaitInterval:           1,
+	TxReceiptMaxRetries:             30,
+	InsufficientBalanceWaitInterval: 30,
+	Pipeline:                        1,
+}
+
+// LoopConfig ...
+// tunes the timing and batching of a relay loop, intervals are in seconds;
+// zero values take the defaults of the destination chain in LoopDefaults,
+// then defaultLoopConfig. Without a loop config, the relay keeps the timing
+// of defaultLoopConfig, an empty one opts in to the chain defaults
+type LoopConfig struct {
+	TickerInterval                  float64 `json:"ticker_interval"`                    // between relay attempts
+	BalanceCheckInterval            float64 `json:"balance_check_interval"`             // between wallet balance checks
+	TriggerReceiptsCount            uint    `json:"trigger_receipts_count"`             // pending receipts relayed before the next tick
+	TxSendWaitInterval              float64 `json:"tx_send_wait_interval"`              // between tx.Send retries
+	TxReceiptWaitInterval           float64 `json:"tx_receipt_wait_interval"`           // between tx.Receipt polls
+	TxReceiptMaxRetries             uint    `json:"tx_receipt_max_retries"`             // tx.Receipt polls before giving up on a tx
+	InsufficientBalanceWaitInterval float64 `json:"insufficient_balance_wait_interval"` // before retrying with insufficient balance
+	Pipeline                        uint    `json:"pipeline"`                           // relay txs in flight, if the sender is a chain.PipelinedSender
+}
+
+// LoopDefaults ...
+// maps destination chain names to their relay loop defaults,
+// registered along with the chain's sender; they apply to the relays
+// with a loop config only
+var LoopDefaults = map[string]LoopConfig{}
+
+// merge ...
+// returns "cfg" with its zero values taken from "defaults"
+func (cfg LoopConfig) merge(defaults LoopConfig) LoopConfig {
+	if cfg.TickerInterval == 0 {
+		cfg.TickerInterval = defaults.TickerInterval
+	}
+	if cfg.BalanceCheckInterval == 0 {
+		cfg.BalanceCheckInterval = defaults.BalanceCheckInterval
+	}
+	if cfg.TriggerReceiptsCount == 0 {
+		cfg.TriggerReceiptsCount = defaults.TriggerReceiptsCount
+	}
+	if cfg.TxSendWaitInterval == 0 {
+		cfg.TxSendWaitInterval = defaults.TxSendWaitInterval
+	}
+	if cfg.TxReceiptWaitInterval == 0 {
+		cfg.TxReceiptWaitInterval = defaults.TxReceiptWaitInterval
+	}
+	if cfg.TxReceiptMaxRetries == 0 {
+		cfg.TxReceiptMaxRetries = defaults.TxReceiptMaxRetries
+	}
+	if cfg.InsufficientBalanceWaitInterval == 0 {
+		cfg.InsufficientBalanceWaitInterval = defaults.InsufficientBalanceWaitInterval
+	}
+	if cfg.Pipeline == 0 {
+		cfg.Pipeline = defaults.Pipeline
+	}
+	return cfg
+}
+
+func (cfg LoopConfig) validate() error {
+	for name, v := range map[string]float64{
+		"ticker_interval":                    cfg.TickerInterval,
+		"balance_check_interval":             cfg.BalanceCheckInterval,
+		"tx_send_wait_interval":              cfg.TxSendWaitInterval,
+		"tx_receipt_wait_interval":           cfg.TxReceiptWaitInterval,
+		"insufficient_balance_wait_interval": cfg.InsufficientBalanceWaitInterval,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative: %v", name, v)
+		}
+	}
+	if d := seconds(cfg.TickerInterval); d < relayMinTickerInterval {
+		return fmt.Errorf("ticker_interval must be at least %v: %v", relayMinTickerInterval, d)
+	}
+	if cfg.BalanceCheckInterval < cfg.TickerInterval {
+		return fmt.Errorf("balance_check_interval %v must not be less than ticker_interval %v",
+			cfg.BalanceCheckInterval, cfg.TickerInterval)
+	}
+	return nil
+}
+
+// loopOptions ...
+// is a validated LoopConfig with all the defaults applied
+type loopOptions struct {
+	tickerInterval                  time.Duration
+	balanceCheckInterval            time.Duration
+	triggerReceiptsCount            int
+	txSendWaitInterval              time.Duration
+	txReceiptWaitInterval           time.Duration
+	txReceiptMaxRetries             int
+	insufficientBalanceWaitInterval time.Duration
+	pipeline                        int
+}
+
+// newLoopOptions ...
+// applies the defaults of the destination chain "dstChain", then the
+// global ones, to the relay's loop config "cfg"; if it's nil, only the
+// global ones apply
+func newLoopOptions(cfg *LoopConfig, dstChain string) (loopOptions, error) {
+	var c LoopConfig
+	if cfg != nil {
+		c = cfg.merge(LoopDefaults[dstChain])
+	}
+	c = c.merge(defaultLoopConfig)
+	if err := c.validate(); err != nil {
+		return loopOptions{}, err
+	}
+	return loopOptions{
+		tickerInterval:                  seconds(c.TickerInterval),
+		balanceCheckInterval:            seconds(c.BalanceCheckInterval),
+		triggerReceiptsCount:            int(c.TriggerReceiptsCount),
+		txSendWaitInterval:              seconds(c.TxSendWaitInterval),
+		txReceiptWaitInterval:           seconds(c.TxReceiptWaitInterval),
+		txReceiptMaxRetries:             int(c.TxReceiptMaxRetries),
+		insufficientBalanceWaitInterval: seconds(c.InsufficientBalanceWaitInterval),
+		pipeline:                        int(c.Pipeline),
+	}, nil
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
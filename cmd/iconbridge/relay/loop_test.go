@@ -0,0 +1,38 @@
+package relay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoopOptions(t *testing.T) {
+	LoopDefaults["test"] = LoopConfig{TickerInterval: 2, TxReceiptMaxRetries: 10}
+	defer delete(LoopDefaults, "test")
+
+	opts, err := newLoopOptions(&LoopConfig{TickerInterval: 1, TxSendWaitInterval: 0.25}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, opts.tickerInterval)
+	assert.Equal(t, time.Second/4, opts.txSendWaitInterval)
+	assert.Equal(t, 10, opts.txReceiptMaxRetries)
+	assert.Equal(t, 60*time.Second, opts.balanceCheckInterval)
+
+	opts, err = newLoopOptions(&LoopConfig{}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, opts.tickerInterval)
+
+	// without a loop config, the chain defaults don't apply
+	opts, err = newLoopOptions(nil, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, opts.tickerInterval)
+	assert.Equal(t, 30, opts.txReceiptMaxRetries)
+
+	_, err = newLoopOptions(&LoopConfig{TxReceiptWaitInterval: -1}, "test")
+	assert.Error(t, err)
+	_, err = newLoopOptions(&LoopConfig{TickerInterval: 0.01}, "test")
+	assert.Error(t, err)
+	_, err = newLoopOptions(&LoopConfig{TickerInterval: 120}, "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,237 @@
+package relay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metricsNamespace   = "iconbridge_relay_"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	metricsTxReceiptLatencyBuckets = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300}
+)
+
+// relayMetrics ...
+// are the runtime metrics of all relays labelled by relay name,
+// exposed in the prometheus text format by MetricsHandler
+var relayMetrics = struct {
+	srcHeight        *gaugeVec
+	dstRxSeq         *gaugeVec
+	dstRxHeight      *gaugeVec
+	pendingReceipts  *gaugeVec
+	txsSent          *gaugeVec
+	txsFailed        *gaugeVec
+	txsRetried       *gaugeVec
+	txReceiptLatency *histogramVec
+	balance          *gaugeVec
+	balanceThreshold *gaugeVec
+	heldSeq          *gaugeVec
+	txFees           *gaugeVec
+	runway           *gaugeVec
+}{
+	srcHeight:        newGaugeVec("src_height", "gauge", "last scanned source chain height"),
+	dstRxSeq:         newGaugeVec("dst_rx_seq", "gauge", "last sequence received by the destination BMC"),
+	dstRxHeight:      newGaugeVec("dst_rx_height", "gauge", "last source height received by the destination BMC"),
+	pendingReceipts:  newGaugeVec("pending_receipts", "gauge", "receipts waiting to be relayed"),
+	txsSent:          newGaugeVec("txs_sent_total", "counter", "relay txs sent"),
+	txsFailed:        newGaugeVec("txs_failed_total", "counter", "relay txs failed or reverted"),
+	txsRetried:       newGaugeVec("txs_retried_total", "counter", "relay tx send retries"),
+	txReceiptLatency: newHistogramVec("tx_receipt_latency_seconds", "time from sending a relay tx to its receipt", metricsTxReceiptLatencyBuckets),
+	balance:          newGaugeVec("wallet_balance", "gauge", "relay wallet balance"),
+	balanceThreshold: newGaugeVec("wallet_balance_threshold", "gauge", "relay wallet balance threshold"),
+	txFees:           newGaugeVec("tx_fees_total", "counter", "fees paid for relay txs, in the smallest unit of the destination coin"),
+	runway:           newGaugeVec("wallet_runway_seconds", "gauge", "forecast time until the relay wallet is empty, 0 if nothing is spent"),
+	heldSeq:          newGaugeVec("held_message_seq", "gauge", "sequence of the message held by the relay policy, 0 if none"),
+}
+
+// MetricsHandler ...
+// serves the relay metrics in the prometheus text exposition format
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		bw := bufio.NewWriter(w)
+		m := &relayMetrics
+		for _, g := range []*gaugeVec{
+			m.srcHeight, m.dstRxSeq, m.dstRxHeight, m.pendingReceipts,
+			m.txsSent, m.txsFailed, m.txsRetried,
+			m.balance, m.balanceThreshold, m.heldSeq, m.txFees, m.runway,
+		} {
+			g.write(bw)
+		}
+		m.txReceiptLatency.write(bw)
+		bw.Flush()
+	})
+}
+
+// removeMetrics ...
+// drops the series of a relay that is no longer running
+func removeMetrics(name string) {
+	m := &relayMetrics
+	for _, g := range []*gaugeVec{
+		m.srcHeight, m.dstRxSeq, m.dstRxHeight, m.pendingReceipts,
+		m.txsSent, m.txsFailed, m.txsRetried,
+		m.balance, m.balanceThreshold, m.heldSeq, m.txFees, m.runway,
+	} {
+		g.delete(name)
+	}
+	m.txReceiptLatency.delete(name)
+}
+
+func bigToFloat(v *big.Int) float64 {
+	if v == nil {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(v).Float64()
+	return f
+}
+
+// gaugeVec ...
+// is a gauge or counter with a single "relay" label
+type gaugeVec struct {
+	name, typ, help string
+
+	mtx    sync.Mutex
+	values map[string]float64
+}
+
+func newGaugeVec(name, typ, help string) *gaugeVec {
+	return &gaugeVec{
+		name:   metricsNamespace + name,
+		typ:    typ,
+		help:   help,
+		values: make(map[string]float64),
+	}
+}
+
+func (g *gaugeVec) set(relay string, v float64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.values[relay] = v
+}
+
+func (g *gaugeVec) add(relay string, v float64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.values[relay] += v
+}
+
+func (g *gaugeVec) inc(relay string) {
+	g.add(relay, 1)
+}
+
+func (g *gaugeVec) delete(relay string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	delete(g.values, relay)
+}
+
+func (g *gaugeVec) write(w io.Writer) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", g.name, g.help, g.name, g.typ)
+	for _, relay := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s{relay=\"%s\"} %s\n", g.name, escapeLabel(relay), formatFloat(g.values[relay]))
+	}
+}
+
+type histogram struct {
+	counts []uint64 // cumulative counts per bucket
+	count  uint64
+	sum    float64
+}
+
+// histogramVec ...
+// is a histogram with a single "relay" label
+type histogramVec struct {
+	name, help string
+	buckets    []float64
+
+	mtx    sync.Mutex
+	values map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64) *histogramVec {
+	return &histogramVec{
+		name:    metricsNamespace + name,
+		help:    help,
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+func (h *histogramVec) observe(relay string, d time.Duration) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	v, ok := h.values[relay]
+	if !ok {
+		v = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[relay] = v
+	}
+	secs := d.Seconds()
+	for i, le := range h.buckets {
+		if secs <= le {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += secs
+}
+
+func (h *histogramVec) delete(relay string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	delete(h.values, relay)
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	relays := make([]string, 0, len(h.values))
+	for relay := range h.values {
+		relays = append(relays, relay)
+	}
+	sort.Strings(relays)
+	for _, relay := range relays {
+		v, label := h.values[relay], escapeLabel(relay)
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{relay=\"%s\",le=\"%s\"} %d\n", h.name, label, formatFloat(le), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{relay=\"%s\",le=\"+Inf\"} %d\n", h.name, label, v.count)
+		fmt.Fprintf(w, "%s_sum{relay=\"%s\"} %s\n", h.name, label, formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count{relay=\"%s\"} %d\n", h.name, label, v.count)
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel ...
+// escapes a label value as the text exposition format does: only
+// backslashes, double quotes and line feeds, unlike Go quoting
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
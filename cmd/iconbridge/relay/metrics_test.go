@@ -0,0 +1,84 @@
+package relay
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	defer removeMetrics("m2b")
+
+	relayMetrics.dstRxSeq.set("m2b", 42)
+	relayMetrics.txsSent.inc("m2b")
+	relayMetrics.txsSent.inc("m2b")
+	relayMetrics.txReceiptLatency.observe("m2b", 3*time.Second)
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	assert.Equal(t, metricsContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, body, "# TYPE iconbridge_relay_dst_rx_seq gauge\n")
+	assert.Contains(t, body, "iconbridge_relay_dst_rx_seq{relay=\"m2b\"} 42\n")
+	assert.Contains(t, body, "# TYPE iconbridge_relay_txs_sent_total counter\n")
+	assert.Contains(t, body, "iconbridge_relay_txs_sent_total{relay=\"m2b\"} 2\n")
+	assert.Contains(t, body, "iconbridge_relay_tx_receipt_latency_seconds_bucket{relay=\"m2b\",le=\"2\"} 0\n")
+	assert.Contains(t, body, "iconbridge_relay_tx_receipt_latency_seconds_bucket{relay=\"m2b\",le=\"5\"} 1\n")
+	assert.Contains(t, body, "iconbridge_relay_tx_receipt_latency_seconds_count{relay=\"m2b\"} 1\n")
+
+	removeMetrics("m2b")
+	w = httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.False(t, strings.Contains(w.Body.String(), "m2b"))
+}
+
+func TestMetricsEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabel("a\\b\"c\nd"))
+	assert.Equal(t, "é\t", escapeLabel("é\t"), "only backslashes, quotes and line feeds are escaped")
+
+	defer removeMetrics("é\"2b")
+	relayMetrics.txsSent.inc("é\"2b")
+	relayMetrics.txReceiptLatency.observe("é\"2b", time.Second)
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, "iconbridge_relay_txs_sent_total{relay=\"é\\\"2b\"} 1\n")
+	assert.Contains(t, body, "iconbridge_relay_tx_receipt_latency_seconds_count{relay=\"é\\\"2b\"} 1\n")
+}
+
+func TestMetricsSrcHeight(t *testing.T) {
+	defer removeMetrics("h2b")
+
+	// the source height follows the blocks scanned, with or without events
+	src := &scriptReceiver{scripts: []func(chain.SubscribeOptions, func(*chain.Message) bool){
+		func(opts chain.SubscribeOptions, send func(*chain.Message) bool) {
+			send(&chain.Message{Receipts: []*chain.Receipt{{Height: 10, Events: []*chain.Event{{Sequence: 1}}}}})
+			for h := uint64(11); h <= 50; h++ {
+				send(&chain.Message{Height: h})
+			}
+		},
+	}}
+	r, err := newRelay(&RelayConfig{Name: "h2b"}, src, &segmentSender{}, nil, log.New())
+	require.NoError(t, err)
+	r.ctl.pause(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- r.Start(ctx) }()
+	require.Eventually(t, func() bool {
+		relayMetrics.srcHeight.mtx.Lock()
+		defer relayMetrics.srcHeight.mtx.Unlock()
+		return relayMetrics.srcHeight.values["h2b"] == 50
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(50), r.Status().SrcHeight)
+	cancel()
+	<-errCh
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/db"
 	"github.com/icon-project/icon-bridge/common/log"
 	"github.com/icon-project/icon-bridge/common/wallet"
 )
@@ -35,108 +38,661 @@ var (
 	Receivers = map[string]NewReceiverFunc{}
 )
 
-func NewMultiRelay(cfg *Config, l log.Logger) (Relay, error) {
-	mr := &multiRelay{log: l}
+// MultiRelay ...
+// runs all configured relays, restarting them on failure
+type MultiRelay interface {
+	Relay
+	Status() []*RelayStatus
+	Pause(name string) error
+	Resume(name string) error
+	Restart(name string) error
+	Drain(name string) error
+	SetShadow(name string, shadow bool) error
+	Release(name string, seq uint64) error
+	Reload(cfg *Config) error
+	Journal(q *JournalQuery) ([]*JournalEntry, error)
 
-	for _, rc := range cfg.Relays {
+	// Shutdown ...
+	// drains all the relays and waits until they're stopped or "ctx" is
+	// done; Start returns once its context is cancelled afterwards
+	Shutdown(ctx context.Context) error
+
+	// Events ...
+	// returns the lifecycle events of relay "name", or of all the relays
+	// if empty, until "cancel" is called or the receiver falls behind
+	Events(name string) (events <-chan *Event, cancel func())
+}
 
-		var dst chain.Sender
-		var src chain.Receiver
+func NewMultiRelay(cfg *Config, l log.Logger) (MultiRelay, error) {
+	mr := &multiRelay{log: l, restart: cfg.Restart, cfg: *cfg, ev: newEventBus()}
 
-		w, err := rc.Dst.Wallet()
+	if cfg.DB != nil {
+		database, err := db.Open(cfg.DB.Dir, cfg.DB.Type, cfg.DB.Name)
 		if err != nil {
-			return nil, fmt.Errorf("dst.wallet chain %v err %v", rc.Name, err)
+			return nil, fmt.Errorf("db.Open type %v err %v", cfg.DB.Type, err)
 		}
-		chainName := rc.Dst.Address.BlockChain()
-		srvName := "BMR-"
-		if strings.ToUpper(chainName) == "ICON" {
-			srvName += strings.ToUpper(rc.Src.Address.BlockChain())
-		} else {
-			srvName += strings.ToUpper(chainName)
-		}
-		l := l.WithFields(log.Fields{
-			log.FieldKeyModule:  rc.Name,
-			log.FieldKeyWallet:  w.Address(),
-			log.FieldKeyService: srvName,
-		})
-
-		if sender, ok := Senders[chainName]; ok {
-			if dst, err = sender(
-				rc.Src.Address,
-				rc.Dst.Address,
-				rc.Dst.Endpoint,
-				w,
-				rc.Dst.Options,
-				l.WithFields(log.Fields{
-					log.FieldKeyPrefix: "tx_",
-					log.FieldKeyChain:  chainName,
-				})); err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, fmt.Errorf("unsupported blockchain: sender=%s", chainName)
-		}
-
-		chainName = rc.Src.Address.BlockChain()
-		if receiver, ok := Receivers[chainName]; ok {
-			if src, err = receiver(
-				rc.Src.Address,
-				rc.Dst.Address,
-				rc.Src.Endpoint,
-				rc.Src.Options,
-				l.WithFields(log.Fields{
-					log.FieldKeyPrefix: "rx_",
-					log.FieldKeyChain:  chainName,
-				}),
-			); err != nil {
-				return nil, err
+		if mr.cps, err = NewCheckpointStore(database); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("checkpoint store err %v", err)
+		}
+		if mr.qbk, err = database.GetBucket(QueueBucket); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("queue bucket err %v", err)
+		}
+		if mr.pbk, err = database.GetBucket(PolicyBucket); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("policy bucket err %v", err)
+		}
+		if mr.js, err = NewJournalStore(database, cfg.Journal); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("journal store err %v", err)
+		}
+		mr.db = database
+	}
+
+	if cfg.Election != nil {
+		elector, err := NewElector(cfg.Election, mr.db)
+		if err != nil {
+			if mr.db != nil {
+				mr.db.Close()
 			}
-		} else {
-			return nil, fmt.Errorf("unsupported blockchain: receiver=%s", chainName)
+			return nil, fmt.Errorf("elector type %v err %v", cfg.Election.Type, err)
 		}
+		mr.elector, mr.leaseTTL = elector, cfg.Election.leaseTTL()
+	}
 
-		relay, err := NewRelay(rc, src, dst, l.WithFields(log.Fields{log.FieldKeyChain: "relay"}))
+	for _, rc := range cfg.Relays {
+		relay, err := mr.newRelay(rc, cfg.Restart)
 		if err != nil {
+			for _, relay := range mr.relays {
+				relay.close()
+			}
+			if mr.db != nil {
+				mr.db.Close()
+			}
 			return nil, err
 		}
 		mr.relays = append(mr.relays, relay)
-
 	}
 
 	return mr, nil
 }
 
+// newRelay ...
+// creates the sender and receiver of a relay from its config
+func (mr *multiRelay) newRelay(rc *RelayConfig, restart *RestartConfig) (*relay, error) {
+	if rc.Rotation != nil {
+		if err := rc.Rotation.validate(); err != nil {
+			return nil, fmt.Errorf("rotation chain %v err %v", rc.Name, err)
+		}
+	}
+
+	src, dst, l, err := newChains(rc, mr.log)
+	if err != nil {
+		return nil, err
+	}
+
+	relay, err := newRelay(rc, src, dst, mr.cps, l.WithFields(log.Fields{log.FieldKeyChain: "relay"}))
+	if err != nil {
+		src.Close()
+		dst.Close()
+		return nil, fmt.Errorf("relay %v err %v", rc.Name, err)
+	}
+	if rc.Restart == nil {
+		relay.rp = newRestartPolicy(restart)
+	}
+	relay.q.bk = mr.qbk
+	if relay.pol != nil {
+		relay.pol.bk = mr.pbk
+	}
+	relay.js = mr.js
+	relay.ev = mr.ev
+	// stand by until the first election
+	relay.ctl.standby = mr.elector != nil
+	return relay, nil
+}
+
+// newChains ...
+// creates the receiver and sender of a relay from its config, and returns
+// the logger of the relay derived from "l"
+func newChains(rc *RelayConfig, l log.Logger) (src chain.Receiver, dst chain.Sender, _ log.Logger, err error) {
+	w, err := rc.Dst.Wallet()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("dst.wallet chain %v err %v", rc.Name, err)
+	}
+	chainName := rc.Dst.Address.BlockChain()
+	srvName := "BMR-"
+	if strings.ToUpper(chainName) == "ICON" {
+		srvName += strings.ToUpper(rc.Src.Address.BlockChain())
+	} else {
+		srvName += strings.ToUpper(chainName)
+	}
+	l = l.WithFields(log.Fields{
+		log.FieldKeyModule:  rc.Name,
+		log.FieldKeyWallet:  w.Address(),
+		log.FieldKeyService: srvName,
+	})
+
+	if sender, ok := Senders[chainName]; ok {
+		if dst, err = sender(
+			rc.Src.Address,
+			rc.Dst.Address,
+			rc.Dst.Endpoint,
+			w,
+			rc.Dst.Options,
+			l.WithFields(log.Fields{
+				log.FieldKeyPrefix: "tx_",
+				log.FieldKeyChain:  chainName,
+			})); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		return nil, nil, nil, fmt.Errorf("unsupported blockchain: sender=%s", chainName)
+	}
+
+	chainName = rc.Src.Address.BlockChain()
+	if receiver, ok := Receivers[chainName]; ok {
+		if src, err = receiver(
+			rc.Src.Address,
+			rc.Dst.Address,
+			rc.Src.Endpoint,
+			rc.Src.Options,
+			l.WithFields(log.Fields{
+				log.FieldKeyPrefix: "rx_",
+				log.FieldKeyChain:  chainName,
+			}),
+		); err != nil {
+			dst.Close()
+			return nil, nil, nil, err
+		}
+	} else {
+		dst.Close()
+		return nil, nil, nil, fmt.Errorf("unsupported blockchain: receiver=%s", chainName)
+	}
+	return src, dst, l, nil
+}
+
 type multiRelay struct {
-	log    log.Logger
-	relays []Relay
+	log      log.Logger
+	db       db.Database
+	cps      CheckpointStore
+	qbk      db.Bucket // spilled relay queues
+	pbk      db.Bucket // held and approved messages of the relay policies
+	js       JournalStore
+	elector  Elector
+	leaseTTL time.Duration
+	restart  *RestartConfig // default restart policy of the relays
+	cfg      Config         // sections applied at start, a reload leaves them unchanged
+
+	ev *eventBus
+
+	reloadMtx sync.Mutex // serializes reloads, which create relays without holding mtx
+
+	mtx      sync.Mutex
+	relays   []*relay
+	ctx      context.Context // set by Start to run stopped relays again
+	shutdown bool            // relays are draining before exit, none may start
+	wg       sync.WaitGroup
+}
+
+func (mr *multiRelay) Status() []*RelayStatus {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	sts := make([]*RelayStatus, 0, len(mr.relays))
+	for _, r := range mr.relays {
+		sts = append(sts, r.Status())
+	}
+	return sts
 }
 
 func (mr *multiRelay) Start(ctx context.Context) error {
-	rch := make(chan Relay, len(mr.relays))
+	if mr.db != nil {
+		defer mr.db.Close()
+	}
+	mr.mtx.Lock()
+	mr.ctx = ctx
 	for _, relay := range mr.relays {
-		rch <- relay
+		mr.run(ctx, relay)
+	}
+	mr.mtx.Unlock()
+
+	if mr.elector != nil {
+		mr.elect()
+		go mr.runElection(ctx)
 	}
+
+	<-ctx.Done()
+	mr.wg.Wait()
+	if mr.elector != nil {
+		mr.releaseLeases()
+	}
+	mr.mtx.Lock()
+	for _, relay := range mr.relays {
+		relay.close()
+	}
+	mr.mtx.Unlock()
+	return ctx.Err()
+}
+
+func (mr *multiRelay) runElection(ctx context.Context) {
+	ticker := time.NewTicker(mr.leaseTTL / 3)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case r := <-rch:
-			go func(relay Relay) {
-				defer func() {
-					if r := recover(); r != nil {
-						debug.PrintStack()
-						rch <- relay
-					}
-				}()
-				if err := relay.Start(ctx); err != nil {
-					if !errors.Is(err, context.Canceled) {
-						mr.log.Errorf("%v", err)
-						mr.log.Info("restarting relay in 5s...")
-						time.Sleep(5 * time.Second)
-						rch <- relay
-					}
+			return
+		case <-ticker.C:
+			mr.elect()
+		}
+	}
+}
+
+// elect ...
+// acquires or renews the lease of every relay running and subscribed to its
+// source, the relays leased by another instance stand by in shadow mode
+// until their lease expires; the lease of a relay that isn't subscribed,
+// e.g. restarting, isn't renewed, and a stopped or quarantined relay gives
+// it up, so that a healthy instance takes over
+func (mr *multiRelay) elect() {
+	mr.mtx.Lock()
+	relays := append([]*relay(nil), mr.relays...)
+	mr.mtx.Unlock()
+	for _, relay := range relays {
+		switch relay.ctl.state() {
+		case RelayStateStopped, RelayStateQuarantined:
+			if err := mr.elector.Release(relay.cfg.Name); err != nil {
+				relay.log.WithFields(log.Fields{"error": err}).Warn("election: failed to release lease")
+			}
+			relay.ctl.setStandby(true)
+			continue
+		}
+		if !relay.state.isSubscribed() {
+			if relay.ctl.setStandby(true) {
+				relay.log.Info("election: not subscribed, standing by")
+			}
+			continue
+		}
+		leader, err := mr.elector.Acquire(relay.cfg.Name, mr.leaseTTL)
+		if err != nil {
+			relay.log.WithFields(log.Fields{"error": err}).Warn("election: failed to acquire lease")
+		}
+		if relay.ctl.setStandby(!leader) {
+			if leader {
+				relay.log.Info("election: acquired lease, relaying")
+			} else {
+				relay.log.Info("election: lease held by another instance, standing by")
+			}
+		}
+	}
+}
+
+func (mr *multiRelay) releaseLeases() {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	for _, relay := range mr.relays {
+		if err := mr.elector.Release(relay.cfg.Name); err != nil {
+			relay.log.WithFields(log.Fields{"error": err}).Warn("election: failed to release lease")
+		}
+	}
+}
+
+// run ...
+// runs the relay in its own goroutine, restarting it on failure, until
+// it's drained or "ctx" is done
+func (mr *multiRelay) run(ctx context.Context, relay *relay) {
+	if mr.shutdown {
+		return
+	}
+	ctx, stop := context.WithCancel(ctx)
+	if !relay.ctl.activate(stop) {
+		stop()
+		return
+	}
+	mr.wg.Add(1)
+	go func() {
+		defer mr.wg.Done()
+		defer relay.ctl.deactivate()
+		defer stop()
+		for {
+			rctx, cancel := context.WithCancel(ctx)
+			relay.ctl.started(cancel)
+			started := time.Now()
+			err := mr.start(rctx, relay)
+			cancel()
+			restart := relay.ctl.stopped()
+			switch {
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, errRelayDrained):
+				relay.log.Info("relay drained: stopped")
+				return
+			case restart:
+				relay.log.Info("restarting relay")
+			default:
+				delay, quarantine := relay.rp.failed(started, time.Now())
+				failures := relay.rp.consecutiveFailures()
+				relay.state.crashed(err, failures)
+				l := relay.log.WithFields(log.Fields{
+					"error":    err,
+					"failures": failures,
+				})
+				if quarantine {
+					relay.ctl.setQuarantined(true)
+					l.Errorf("relay quarantined: restarting in %v", delay)
+				} else {
+					l.Errorf("relay failed: restarting in %v", delay.Truncate(time.Millisecond))
+				}
+				if !mr.backoff(ctx, relay, delay) {
+					return
+				}
+				if quarantine {
+					relay.ctl.setQuarantined(false)
+					relay.rp.reset()
+					relay.log.Info("relay released from quarantine")
 				}
-			}(r)
+			}
+			relay.state.restarted()
+			e := &Event{}
+			if err != nil && !restart {
+				e.Error = err.Error()
+			}
+			relay.event(EventRestarted, e)
 		}
+	}()
+}
+
+// backoff ...
+// waits "delay" before restarting the relay, unless a restart is requested
+// meanwhile; returns false if the relay must not be restarted
+func (mr *multiRelay) backoff(ctx context.Context, relay *relay, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-relay.ctl.drained():
+		relay.log.Info("relay drained: stopped")
+		return false
+	case <-relay.ctl.woken():
+	case <-timer.C:
+	}
+	return true
+}
+
+// start ...
+// runs the relay once, and recovers if it panics
+func (mr *multiRelay) start(ctx context.Context, relay *relay) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			relay.log.WithFields(log.Fields{
+				"panic": fmt.Sprintf("%v", r),
+				"stack": string(debug.Stack()),
+			}).Error("relay panicked")
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return relay.Start(ctx)
+}
+
+func (mr *multiRelay) find(name string) (*relay, error) {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	for _, relay := range mr.relays {
+		if relay.cfg.Name == name {
+			return relay, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRelayNotFound, name)
+}
+
+// Pause ...
+// stops sending relay messages, while the relay keeps scanning its source
+func (mr *multiRelay) Pause(name string) error {
+	relay, err := mr.find(name)
+	if err != nil {
+		return err
+	}
+	relay.ctl.pause(true)
+	relay.log.Info("relay paused")
+	return nil
+}
+
+// Resume ...
+// resumes a paused relay, or starts it again if it was drained
+func (mr *multiRelay) Resume(name string) error {
+	relay, err := mr.find(name)
+	if err != nil {
+		return err
+	}
+	relay.ctl.pause(false)
+	relay.ctl.wake()
+	relay.log.Info("relay resumed")
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.ctx != nil && mr.ctx.Err() == nil {
+		mr.run(mr.ctx, relay)
+	}
+	return nil
+}
+
+// Restart ...
+// aborts the relay, including any in-flight tx, and starts it again
+func (mr *multiRelay) Restart(name string) error {
+	relay, err := mr.find(name)
+	if err != nil {
+		return err
+	}
+	relay.log.Info("relay restart requested")
+	if relay.ctl.requestRestart() {
+		return nil
+	}
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.ctx == nil || mr.ctx.Err() != nil {
+		return ErrRelayNotRunning
+	}
+	mr.run(mr.ctx, relay)
+	return nil
+}
+
+// Drain ...
+// stops the relay without restarting it once its in-flight tx is done
+func (mr *multiRelay) Drain(name string) error {
+	relay, err := mr.find(name)
+	if err != nil {
+		return err
+	}
+	if err := relay.ctl.drain(); err != nil {
+		return err
+	}
+	relay.log.Info("relay draining")
+	return nil
+}
+
+// SetShadow ...
+// switches the relay between shadow mode, where it only logs the relay
+// messages it would have sent, and active mode
+func (mr *multiRelay) SetShadow(name string, shadow bool) error {
+	relay, err := mr.find(name)
+	if err != nil {
+		return err
+	}
+	relay.ctl.setShadow(shadow)
+	relay.log.WithFields(log.Fields{"shadow": shadow}).Info("relay mode changed")
+	return nil
+}
+
+// Release ...
+// lets the message "seq" held by the relay policy through
+func (mr *multiRelay) Release(name string, seq uint64) error {
+	relay, err := mr.find(name)
+	if err != nil {
+		return err
+	}
+	if err := relay.release(seq); err != nil {
+		return err
+	}
+	relay.ctl.wake()
+	return nil
+}
+
+func (mr *multiRelay) Events(name string) (<-chan *Event, func()) {
+	return mr.ev.subscribe(name)
+}
+
+// Journal ...
+// returns the journal entries of "q.Relay", or of all the relays in turn
+// if empty, up to "q.Limit" per relay
+func (mr *multiRelay) Journal(q *JournalQuery) ([]*JournalEntry, error) {
+	if mr.js == nil {
+		return nil, ErrJournalDisabled
+	}
+	if q.Relay != "" {
+		return mr.js.Query(q)
+	}
+	mr.mtx.Lock()
+	relays := append([]*relay(nil), mr.relays...)
+	mr.mtx.Unlock()
+	var entries []*JournalEntry
+	for _, relay := range relays {
+		rq := *q
+		rq.Relay = relay.cfg.Name
+		es, err := mr.js.Query(&rq)
+		if err != nil {
+			return nil, fmt.Errorf("relay %s: %v", relay.cfg.Name, err)
+		}
+		entries = append(entries, es...)
+	}
+	return entries, nil
+}
+
+func (mr *multiRelay) Shutdown(ctx context.Context) error {
+	mr.mtx.Lock()
+	mr.shutdown = true
+	relays := append([]*relay(nil), mr.relays...)
+	mr.mtx.Unlock()
+
+	mr.log.Info("shutting down: draining relays")
+	for _, relay := range relays {
+		if err := relay.ctl.drain(); err == nil {
+			relay.log.Info("relay draining")
+		}
+	}
+	for _, relay := range relays {
+		select {
+		case <-relay.ctl.done():
+		case <-ctx.Done():
+			var draining []string
+			for _, relay := range relays {
+				if relay.ctl.state() != RelayStateStopped {
+					draining = append(draining, relay.cfg.Name)
+				}
+			}
+			mr.log.WithFields(log.Fields{"relays": draining}).Warn("shutdown deadline exceeded: in-flight txs abandoned")
+			return ctx.Err()
+		}
+	}
+	mr.log.Info("shutting down: all relays drained")
+	return nil
+}
+
+// Reload ...
+// applies a new relay config: added relays are started, removed ones are
+// stopped, and only the relays whose config changed are recreated, so
+// that all the other relays keep running
+func (mr *multiRelay) Reload(cfg *Config) error {
+	mr.reloadMtx.Lock()
+	defer mr.reloadMtx.Unlock()
+
+	mr.mtx.Lock()
+	running := make(map[string]*relay, len(mr.relays))
+	for _, relay := range mr.relays {
+		running[relay.cfg.Name] = relay
+	}
+	mr.mtx.Unlock()
+
+	// create all new relays before touching the running ones, without
+	// blocking the others while connecting to the chains
+	var relays, created []*relay
+	fail := func(err error) error {
+		for _, relay := range created {
+			relay.close()
+		}
+		return err
+	}
+	names := make(map[string]bool, len(cfg.Relays))
+	for _, rc := range cfg.Relays {
+		if names[rc.Name] {
+			return fail(fmt.Errorf("duplicate relay name: %s", rc.Name))
+		}
+		names[rc.Name] = true
+		if old, ok := running[rc.Name]; ok && reflect.DeepEqual(old.cfg, rc) {
+			relays = append(relays, old)
+			continue
+		}
+		relay, err := mr.newRelay(rc, cfg.Restart)
+		if err != nil {
+			return fail(fmt.Errorf("relay %s: %v", rc.Name, err))
+		}
+		relays = append(relays, relay)
+		created = append(created, relay)
+	}
+
+	// swap the relays, then stop the stale ones without holding mtx, so
+	// that the status and the other relays aren't blocked meanwhile
+	mr.mtx.Lock()
+	mr.restart = cfg.Restart
+	var stale []*relay
+	for _, relay := range created {
+		if old, ok := running[relay.cfg.Name]; ok {
+			relay.ctl.pause(old.ctl.isPaused())
+			stale = append(stale, old)
+		}
+	}
+	var added, removed, restarted []string
+	for _, relay := range mr.relays {
+		if !names[relay.cfg.Name] {
+			stale = append(stale, relay)
+			removed = append(removed, relay.cfg.Name)
+		}
+	}
+	mr.relays = relays
+	mr.mtx.Unlock()
+
+	for _, relay := range stale {
+		relay.ctl.stop()
+		relay.close()
+	}
+	for _, name := range removed {
+		removeMetrics(name)
+		if mr.elector != nil {
+			if err := mr.elector.Release(name); err != nil {
+				mr.log.WithFields(log.Fields{"error": err}).Warnf("election: failed to release lease of %s", name)
+			}
+		}
+	}
+
+	mr.mtx.Lock()
+	for _, relay := range created {
+		if _, ok := running[relay.cfg.Name]; ok {
+			restarted = append(restarted, relay.cfg.Name)
+		} else {
+			added = append(added, relay.cfg.Name)
+		}
+		if mr.ctx != nil && mr.ctx.Err() == nil {
+			mr.run(mr.ctx, relay)
+		}
+	}
+	mr.mtx.Unlock()
+
+	if sections := mr.cfg.fixedChanges(cfg); len(sections) > 0 {
+		mr.log.WithFields(log.Fields{"sections": sections}).Warn(
+			"relay config reloaded without the changes of the sections applied at start, restart to apply them")
 	}
+	mr.log.WithFields(log.Fields{
+		"added":     added,
+		"removed":   removed,
+		"restarted": restarted,
+	}).Info("relay config reloaded")
+	return nil
 }
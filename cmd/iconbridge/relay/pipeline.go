@@ -0,0 +1,290 @@
+package relay
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/errors"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+type txOutcome int
+
+const (
+	txDelivered txOutcome = iota
+	txResend              // gas limit increased, the same tx must be sent again
+	txRetry               // not delivered, relay again without waiting for the ticker
+	txFailed              // not delivered
+)
+
+// inflightTx ...
+// is a relay tx sent but not confirmed yet
+type inflightTx struct {
+	tx       chain.RelayTx
+	newMsg   *chain.Message // rest of the message after tx
+	receipts int
+	seqBegin uint64
+	seqEnd   uint64
+	sentAt   time.Time
+}
+
+// send ...
+// sends the relay tx "itx", retrying until it's accepted or "ctx" is cancelled
+func (r *relay) send(ctx context.Context, itx *inflightTx) error {
+	tx := itx.tx
+	for i := 1; ; i++ {
+		err := tx.Send(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			e := txJournalEntry(itx)
+			e.Retry, e.Error = i, err.Error()
+			r.journal(JournalSendRetry, e)
+		}
+		switch {
+		case err == nil:
+			relayMetrics.txsSent.inc(r.cfg.Name)
+			itx.sentAt = time.Now()
+			r.journal(JournalSent, txJournalEntry(itx))
+			return nil
+		case errors.Is(err, context.Canceled):
+			r.log.WithFields(log.Fields{"id": tx.ID(), "error": err}).Error("tx.Send failed")
+			return err
+		case errors.Is(err, chain.ErrBlockGasLimitExceeded):
+			// rejected before it's included, so the gas limit can't be raised
+			relayMetrics.txsFailed.inc(r.cfg.Name)
+			r.log.WithFields(log.Fields{
+				"maxReceipts": r.bl.decrease(itx.receipts),
+			}).Warn("block gas limit exceeded: reduced batch size")
+			return err
+		case errors.Is(err, chain.ErrInsufficientBalance):
+			r.log.WithFields(log.Fields{"error": err}).Errorf(
+				"add balance to relay account: waiting for %v", r.loop.insufficientBalanceWaitInterval)
+			relayMetrics.txsRetried.inc(r.cfg.Name)
+			if err := wait(ctx, r.loop.insufficientBalanceWaitInterval); err != nil {
+				return err
+			}
+		default:
+			relayMetrics.txsRetried.inc(r.cfg.Name)
+			if err := wait(ctx, r.loop.txSendWaitInterval); err != nil { // wait before sending tx
+				return err
+			}
+			if i > retryWarnThreshold {
+				r.log.WithFields(log.Fields{"error": err}).Warnf("tx.Send: retry=%d", i)
+			} else {
+				r.log.WithFields(log.Fields{"error": err}).Debugf("tx.Send: retry=%d", i)
+			}
+		}
+	}
+}
+
+// wait ...
+// waits for "d", or returns the error of "ctx" once it's done
+func wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// confirm ...
+// waits for the receipt of the sent relay tx "itx", and returns the block
+// height it was included in if it's delivered
+func (r *relay) confirm(ctx context.Context, itx *inflightTx) (blockHeight uint64, outcome txOutcome, err error) {
+	tx, txReceipts := itx.tx, itx.receipts
+	failed := func(err error) {
+		e := r.withCost(txJournalEntry(itx), itx)
+		e.Error = err.Error()
+		r.journal(JournalFailed, e)
+	}
+	for retryCount := 0; retryCount < r.loop.txReceiptMaxRetries; retryCount++ {
+		blockHeight, err := tx.Receipt(ctx)
+		switch {
+		case err == nil:
+			e := r.withCost(txJournalEntry(itx), itx)
+			e.Height = blockHeight
+			r.journal(JournalDelivered, e)
+			return blockHeight, txDelivered, nil
+		case errors.Is(err, context.Canceled):
+			r.log.WithFields(log.Fields{"error": err}).Error("tx.Receipt failed")
+			return 0, txFailed, err
+		case errors.Is(err, chain.ErrGasLimitExceeded):
+			failed(err)
+			l := r.log.WithFields(log.Fields{"id": tx.ID()})
+			gasLimit, err := tx.IncreaseGasLimit()
+			if errors.Is(err, chain.ErrGasLimitCeilingReached) {
+				// the batch can't fit into a single tx at the ceiling
+				l.WithFields(log.Fields{
+					"gasLimit":    gasLimit,
+					"maxReceipts": r.bl.decrease(txReceipts),
+				}).Warn("gas limit ceiling reached: reduced batch size")
+				return 0, txRetry, nil
+			} else if err != nil {
+				l.WithFields(log.Fields{"error": err}).Error("tx.IncreaseGasLimit failed")
+				return 0, txFailed, nil
+			}
+			l.WithFields(log.Fields{"gasLimit": gasLimit}).Warn("gas limit exceeded: resending tx with increased gas limit")
+			return 0, txResend, nil
+		case errors.Is(err, chain.ErrBlockGasLimitExceeded):
+			failed(err)
+			r.log.WithFields(log.Fields{
+				"id":          tx.ID(),
+				"maxReceipts": r.bl.decrease(txReceipts),
+			}).Warn("block gas limit exceeded: reduced batch size")
+			return 0, txRetry, nil
+		case errors.Is(err, chain.ErrBMCRevertInvalidSeqNumber):
+			failed(err)
+			// messages already delivered or skipped; resync on next relay
+			r.log.WithFields(log.Fields{"id": tx.ID(), "error": err}).Warn("invalid sequence number: resyncing")
+			return 0, txRetry, nil
+		default:
+			if retryCount == r.loop.txReceiptMaxRetries-1 {
+				failed(err)
+			}
+			if err := wait(ctx, r.loop.txReceiptWaitInterval); err != nil { // wait before asking for receipt
+				return 0, txFailed, err
+			}
+			if retryCount > retryWarnThreshold {
+				r.log.WithFields(log.Fields{"error": err, "retry": retryCount + 1}).Warn("tx.Receipt: ")
+			} else {
+				if strings.Contains(err.Error(), "not found") {
+					r.log.WithFields(log.Fields{"retry": retryCount + 1}).Debug("tx.Receipt: ")
+				} else {
+					r.log.WithFields(log.Fields{"error": err, "retry": retryCount + 1}).Debug("tx.Receipt: ")
+				}
+			}
+		}
+	}
+	return 0, txFailed, nil
+}
+
+// pipeline ...
+// relays "msg" keeping up to loop.pipeline relay txs with consecutive
+// sequence ranges in flight, and calls "delivered" for each confirmed tx
+// in order; when a tx fails, the later ones are rolled back: they're left
+// to fail on the destination and their messages are segmented again on
+// the next relay, after resyncing with the link status; their fees are
+// journaled once their receipts are found, see settle. When the destination
+// rejects a tx for exceeding its block gas limit, no more txs are sent, the
+// ones in flight are confirmed and the rest is relayed in smaller batches.
+// Without pipelining, only the first tx is relayed.
+func (r *relay) pipeline(ctx context.Context, link *chain.BMCLinkStatus, msg *chain.Message,
+	delivered func(newMsg *chain.Message, blockHeight uint64, sentAt time.Time)) (retry bool, err error) {
+
+	var inflight []*inflightTx
+	rollback := func(ctx context.Context, reason string) {
+		if len(inflight) > 1 {
+			r.log.WithFields(log.Fields{
+				"txs":    len(inflight) - 1,
+				"reason": reason,
+			}).Warn("pipeline: rolled back txs in flight")
+		}
+		for _, itx := range inflight[1:] {
+			relayMetrics.txsFailed.inc(r.cfg.Name)
+			r.settle(ctx, itx)
+			e := r.withCost(txJournalEntry(itx), itx)
+			e.Error = reason
+			r.journal(JournalRollback, e)
+		}
+		if ps, ok := r.dst.(chain.PipelinedSender); ok {
+			ps.ResetPipeline()
+		}
+	}
+
+	pending, sent, blocked := msg, 0, false
+	for {
+		for len(inflight) < r.loop.pipeline && len(pending.Receipts) > 0 && !blocked && r.canFill(sent) {
+			tx, newMsg, err := r.dst.Segment(ctx, pending, r.segmentOptions(link))
+			if err != nil {
+				return false, err
+			} else if tx == nil { // ignore if tx is nil
+				break
+			}
+			txReceipts := len(pending.Receipts) - len(newMsg.Receipts)
+			if txReceipts == 0 && len(inflight) > 0 {
+				break
+			}
+			itx := &inflightTx{
+				tx:       tx,
+				newMsg:   newMsg,
+				receipts: txReceipts,
+			}
+			itx.seqBegin, itx.seqEnd = sequenceRange(pending.Receipts[:txReceipts])
+			e := txJournalEntry(itx)
+			e.Messages = btpMessages(pending.Receipts[:txReceipts])
+			r.journal(JournalSegment, e)
+			if err := r.send(ctx, itx); errors.Is(err, chain.ErrBlockGasLimitExceeded) {
+				blocked = true
+				break
+			} else if err != nil {
+				return false, err
+			}
+			inflight = append(inflight, itx)
+			pending, sent = newMsg, sent+1
+			if len(inflight) > 1 {
+				r.log.WithFields(log.Fields{"txs": len(inflight)}).Debug("pipeline: tx sent")
+			}
+		}
+		if len(inflight) == 0 {
+			return blocked, nil
+		}
+
+		head := inflight[0]
+		blockHeight, outcome, err := r.confirm(ctx, head)
+		if err != nil {
+			return false, err
+		}
+		switch outcome {
+		case txDelivered:
+			delivered(head.newMsg, blockHeight, head.sentAt)
+			inflight = inflight[1:]
+		case txResend:
+			relayMetrics.txsFailed.inc(r.cfg.Name)
+			rollback(ctx, "resending tx with increased gas limit")
+			inflight, pending = inflight[:1], head.newMsg
+			if err := r.send(ctx, head); errors.Is(err, chain.ErrBlockGasLimitExceeded) {
+				return true, nil
+			} else if err != nil {
+				return false, err
+			}
+		default:
+			relayMetrics.txsFailed.inc(r.cfg.Name)
+			rollback(ctx, "tx failed")
+			return outcome == txRetry, nil
+		}
+	}
+}
+
+// settle ...
+// waits for the receipt of the rolled back relay tx "itx", if its sender
+// reports costs, so that the fee it's charged despite failing is recorded;
+// gives up after loop.txReceiptMaxRetries, or once "ctx" is done
+func (r *relay) settle(ctx context.Context, itx *inflightTx) {
+	tx, ok := itx.tx.(chain.CostedRelayTx)
+	if !ok {
+		return
+	}
+	for retryCount := 0; retryCount < r.loop.txReceiptMaxRetries; retryCount++ {
+		if _, err := tx.Receipt(ctx); tx.Cost() != nil || errors.Is(err, context.Canceled) {
+			return
+		}
+		if err := wait(ctx, r.loop.txReceiptWaitInterval); err != nil {
+			return
+		}
+	}
+	r.log.WithFields(log.Fields{"id": tx.ID()}).Warn("pipeline: receipt of rolled back tx not found, its fee isn't recorded")
+}
+
+// canFill ...
+// returns whether another relay tx may be sent after "sent" ones in the
+// current relay; only the first one without pipelining, and none if the
+// relay is paused, draining or switched to shadow mode meanwhile
+func (r *relay) canFill(sent int) bool {
+	if sent == 0 {
+		return true
+	}
+	return r.loop.pipeline > 1 && !r.ctl.isPaused() && !r.ctl.isShadow() &&
+		r.ctl.state() != RelayStateDraining && !r.rw.isBelowFloor()
+}
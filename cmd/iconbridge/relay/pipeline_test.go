@@ -0,0 +1,223 @@
+package relay
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pipelinedTx struct {
+	s        *pipelinedSender
+	seqBegin uint64
+	gasLimit uint64
+}
+
+func (tx *pipelinedTx) ID() interface{} { return tx.seqBegin }
+func (tx *pipelinedTx) Send(context.Context) error {
+	if tx.s.broke {
+		return chain.ErrInsufficientBalance
+	}
+	if tx.seqBegin == tx.s.reject {
+		return chain.ErrBlockGasLimitExceeded
+	}
+	tx.s.sent = append(tx.s.sent, tx.seqBegin)
+	tx.s.gasLimits = append(tx.s.gasLimits, tx.gasLimit)
+	tx.s.inflight = append(tx.s.inflight, len(tx.s.sent)-len(tx.s.confirmed))
+	if tx.s.sentCh != nil {
+		select {
+		case tx.s.sentCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+func (tx *pipelinedTx) Receipt(context.Context) (uint64, error) {
+	tx.s.confirmed = append(tx.s.confirmed, tx.seqBegin)
+	if tx.seqBegin == tx.s.fail {
+		return 0, chain.ErrBMCRevertInvalidSeqNumber
+	}
+	if tx.gasLimit < tx.s.gasUsed {
+		return 0, chain.ErrGasLimitExceeded
+	}
+	return tx.seqBegin, nil
+}
+func (tx *pipelinedTx) IncreaseGasLimit() (uint64, error) {
+	if tx.gasLimit >= tx.s.maxGasLimit {
+		return tx.gasLimit, chain.ErrGasLimitCeilingReached
+	}
+	tx.gasLimit *= 2
+	if tx.gasLimit > tx.s.maxGasLimit {
+		tx.gasLimit = tx.s.maxGasLimit
+	}
+	return tx.gasLimit, nil
+}
+func (tx *pipelinedTx) Size() int { return 0 }
+
+// costedTx ...
+// is a pipelinedTx that costs 10 gas at a price of 2 once it's confirmed
+type costedTx struct {
+	*pipelinedTx
+	cost *chain.TxCost
+}
+
+func (tx *costedTx) Receipt(ctx context.Context) (uint64, error) {
+	tx.cost = chain.NewTxCost(10, big.NewInt(2))
+	return tx.pipelinedTx.Receipt(ctx)
+}
+func (tx *costedTx) Cost() *chain.TxCost { return tx.cost }
+
+// pipelinedSender ...
+// packs a receipt into each relay tx, fails the one starting at "fail" and
+// rejects sending the one starting at "reject";
+// txs run out of gas below "gasUsed", and their limit doubles up to "maxGasLimit"
+type pipelinedSender struct {
+	segmentSender
+	fail        uint64
+	reject      uint64
+	gasLimit    uint64
+	gasUsed     uint64
+	maxGasLimit uint64
+	sent        []uint64
+	gasLimits   []uint64 // gas limit of each send
+	confirmed   []uint64
+	inflight    []int // txs in flight after each send
+	resets      int
+	sentCh      chan struct{} // signalled on send, if not nil
+	broke       bool          // sending fails for lack of balance
+	costed      bool          // txs report their cost, see costedTx
+}
+
+func (s *pipelinedSender) Segment(ctx context.Context, msg *chain.Message, opts chain.SegmentOptions) (chain.RelayTx, *chain.Message, error) {
+	if len(msg.Receipts) == 0 {
+		return nil, msg, nil
+	}
+	var tx chain.RelayTx = &pipelinedTx{s: s, seqBegin: msg.Receipts[0].Events[0].Sequence, gasLimit: s.gasLimit}
+	if s.costed {
+		tx = &costedTx{pipelinedTx: tx.(*pipelinedTx)}
+	}
+	return tx, &chain.Message{From: msg.From, Receipts: msg.Receipts[1:]}, nil
+}
+
+func (s *pipelinedSender) ResetPipeline() {
+	s.resets++
+}
+
+func TestPipeline(t *testing.T) {
+	var receipts []*chain.Receipt
+	for seq := uint64(1); seq <= 6; seq++ {
+		receipts = append(receipts, &chain.Receipt{Height: seq, Events: []*chain.Event{{Sequence: seq}}})
+	}
+	newPipelinedRelay := func(s *pipelinedSender, pipeline uint) *relay {
+		r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{Pipeline: pipeline}}, nil, s, nil, log.New())
+		require.NoError(t, err)
+		return r
+	}
+
+	// all the messages are delivered with at most 3 txs in flight
+	s := &pipelinedSender{}
+	r := newPipelinedRelay(s, 3)
+	var delivered []uint64
+	retry, err := r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+		func(newMsg *chain.Message, blockHeight uint64, _ time.Time) {
+			delivered = append(delivered, blockHeight)
+		})
+	require.NoError(t, err)
+	assert.False(t, retry)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, delivered)
+	assert.Equal(t, []int{1, 2, 3, 3, 3, 3}, s.inflight)
+
+	// the txs following a failed one are rolled back
+	s = &pipelinedSender{fail: 3}
+	r = newPipelinedRelay(s, 3)
+	delivered = nil
+	retry, err = r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+		func(newMsg *chain.Message, blockHeight uint64, _ time.Time) {
+			delivered = append(delivered, blockHeight)
+		})
+	require.NoError(t, err)
+	assert.True(t, retry)
+	assert.Equal(t, []uint64{1, 2}, delivered)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, s.sent)
+	assert.Equal(t, 1, s.resets)
+
+	// without pipelining, a single tx is relayed
+	s = &pipelinedSender{}
+	r = newPipelinedRelay(s, 0)
+	_, err = r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+		func(*chain.Message, uint64, time.Time) {})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, s.sent)
+}
+
+func TestPipelineGasLimitExceeded(t *testing.T) {
+	receipts := []*chain.Receipt{{Height: 1, Events: []*chain.Event{{Sequence: 1}}}}
+	relayTo := func(s *pipelinedSender) (delivered []uint64, retry bool) {
+		r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{}}, nil, s, nil, log.New())
+		require.NoError(t, err)
+		retry, err = r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+			func(newMsg *chain.Message, blockHeight uint64, _ time.Time) {
+				delivered = append(delivered, blockHeight)
+			})
+		require.NoError(t, err)
+		return delivered, retry
+	}
+
+	// the tx out of gas is sent again with a higher limit until it fits
+	s := &pipelinedSender{gasLimit: 100, gasUsed: 300, maxGasLimit: 1000}
+	delivered, retry := relayTo(s)
+	assert.False(t, retry)
+	assert.Equal(t, []uint64{1}, delivered)
+	assert.Equal(t, []uint64{1, 1, 1}, s.sent)
+	assert.Equal(t, []uint64{100, 200, 400}, s.gasLimits)
+
+	// at the ceiling, the relay is retried with a smaller batch
+	s = &pipelinedSender{gasLimit: 100, gasUsed: 300, maxGasLimit: 200}
+	delivered, retry = relayTo(s)
+	assert.True(t, retry)
+	assert.Empty(t, delivered)
+	assert.Equal(t, []uint64{100, 200}, s.gasLimits)
+}
+
+func TestPipelineBlockGasLimitExceeded(t *testing.T) {
+	var receipts []*chain.Receipt
+	for seq := uint64(1); seq <= 4; seq++ {
+		receipts = append(receipts, &chain.Receipt{Height: seq, Events: []*chain.Event{{Sequence: seq}}})
+	}
+	s := &pipelinedSender{reject: 3}
+	r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{Pipeline: 3}}, nil, s, nil, log.New())
+	require.NoError(t, err)
+
+	// the txs sent before the rejected one are delivered, and the relay is
+	// retried with a smaller batch
+	var delivered []uint64
+	retry, err := r.pipeline(context.Background(), &chain.BMCLinkStatus{}, &chain.Message{Receipts: receipts},
+		func(newMsg *chain.Message, blockHeight uint64, _ time.Time) {
+			delivered = append(delivered, blockHeight)
+		})
+	require.NoError(t, err)
+	assert.True(t, retry)
+	assert.Equal(t, []uint64{1, 2}, delivered)
+	assert.Equal(t, []uint64{1, 2}, s.sent)
+	assert.Equal(t, uint64(1), r.bl.maxReceipts)
+}
+
+func TestPipelineSendCancel(t *testing.T) {
+	s := &pipelinedSender{broke: true}
+	r, err := newRelay(&RelayConfig{Name: "i2b"}, nil, s, nil, log.New())
+	require.NoError(t, err)
+	require.Greater(t, r.loop.insufficientBalanceWaitInterval, 10*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = r.send(ctx, &inflightTx{tx: &pipelinedTx{s: s, seqBegin: 1}, receipts: 1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.Empty(t, s.sent)
+}
@@ -0,0 +1,554 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/btp"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+const (
+	// PolicyBucket maps the held and approved messages, and the transfers
+	// counted by the circuit breakers, of a relay from relay name
+	PolicyBucket db.BucketID = "relay_policy_"
+
+	defaultCircuitBreakerWindow = time.Hour
+)
+
+var (
+	ErrNoHeldMessage = errors.New("no held message")
+)
+
+// PolicyConfig ...
+// holds or halts a relay on specific BTP messages before they're relayed;
+// amounts are decimal strings in the smallest unit of the coin
+type PolicyConfig struct {
+	HoldAbove       map[string]string       `json:"hold_above,omitempty"`       // coin name to amount, larger transfers are held for approval
+	Services        []string                `json:"services,omitempty"`         // services relayed, a message of any other halts the relay; any if empty
+	CircuitBreakers []*CircuitBreakerConfig `json:"circuit_breakers,omitempty"` // halt the relay on unusual outflows
+}
+
+// CircuitBreakerConfig ...
+// halts the relay when the value of "Coin" transferred within the
+// window would exceed "Limit"
+type CircuitBreakerConfig struct {
+	Coin   string `json:"coin"`
+	Limit  string `json:"limit"`
+	Window uint   `json:"window"` // seconds, an hour if zero
+}
+
+// HeldMessage ...
+// is the BTP message blocking the relay until it's released; since the
+// BMC requires messages in sequence, none of the following ones is relayed
+type HeldMessage struct {
+	Seq     uint64    `json:"seq"`
+	Height  uint64    `json:"height"`
+	Message string    `json:"message"`
+	Reason  string    `json:"reason"`
+	Halted  bool      `json:"halted"` // the relay was paused, nothing is relayed
+	Since   time.Time `json:"since"`
+}
+
+// policy ...
+// is the stage between the messages pending delivery and their
+// segmentation into relay txs; the held and approved messages, and the
+// transfers within the circuit breaker windows, are saved to the relay db,
+// so they survive a restart
+type policy struct {
+	name      string
+	bk        db.Bucket // nil if the relay db isn't configured
+	holdAbove map[string]*big.Int
+	services  map[string]bool // any service if nil
+	breakers  []*circuitBreaker
+
+	mtx        sync.Mutex
+	held       *HeldMessage
+	approved   map[uint64]bool
+	dirty      bool   // held, approved or counted transfers changed since the last save
+	countedSeq uint64 // last delivered sequence counted by the circuit breakers
+}
+
+type policyState struct {
+	Held       *policyHeld
+	Approved   []uint64
+	CountedSeq uint64
+	Breakers   []*policyBreaker
+}
+
+type policyBreaker struct {
+	Coin      string
+	Transfers []*policyTransfer
+}
+
+type policyTransfer struct {
+	At    int64 // unix nano
+	Value []byte
+}
+
+type policyHeld struct {
+	Seq     uint64
+	Height  uint64
+	Message string
+	Reason  string
+	Halted  bool
+	Since   int64 // unix nano
+}
+
+type circuitBreaker struct {
+	coin      string
+	limit     *big.Int
+	window    time.Duration
+	transfers []transfer
+}
+
+type transfer struct {
+	at    time.Time
+	value *big.Int
+}
+
+// newPolicy ...
+// returns the policy of relay "name", or nil if "cfg" is nil, every
+// message is relayed then
+func newPolicy(name string, cfg *PolicyConfig) (*policy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	p := &policy{
+		name:      name,
+		holdAbove: make(map[string]*big.Int),
+		approved:  make(map[uint64]bool),
+	}
+	for coin, s := range cfg.HoldAbove {
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("hold_above %s: invalid amount %q", coin, s)
+		}
+		p.holdAbove[coin] = v
+	}
+	if len(cfg.Services) > 0 {
+		p.services = make(map[string]bool)
+		for _, svc := range cfg.Services {
+			p.services[svc] = true
+		}
+	}
+	for _, bc := range cfg.CircuitBreakers {
+		v, ok := new(big.Int).SetString(bc.Limit, 10)
+		if !ok {
+			return nil, fmt.Errorf("circuit_breakers %s: invalid limit %q", bc.Coin, bc.Limit)
+		}
+		cb := &circuitBreaker{coin: bc.Coin, limit: v, window: time.Duration(bc.Window) * time.Second}
+		if cb.window == 0 {
+			cb.window = defaultCircuitBreakerWindow
+		}
+		p.breakers = append(p.breakers, cb)
+	}
+	return p, nil
+}
+
+// load ...
+// reads the held and approved messages, and the counted transfers still
+// within the circuit breaker windows, saved by a previous run
+func (p *policy) load() error {
+	if p == nil || p.bk == nil {
+		return nil
+	}
+	b, err := p.bk.Get([]byte(p.name))
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	st := &policyState{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, st); err != nil {
+		return err
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.held, p.approved, p.dirty = nil, make(map[uint64]bool), false
+	if h := st.Held; h != nil {
+		p.held = &HeldMessage{
+			Seq:     h.Seq,
+			Height:  h.Height,
+			Message: h.Message,
+			Reason:  h.Reason,
+			Halted:  h.Halted,
+			Since:   time.Unix(0, h.Since),
+		}
+	}
+	for _, seq := range st.Approved {
+		p.approved[seq] = true
+	}
+	p.countedSeq = st.CountedSeq
+	now := time.Now()
+	for _, cb := range p.breakers {
+		cb.transfers = nil
+		for _, sb := range st.Breakers {
+			if sb.Coin != cb.coin {
+				continue
+			}
+			for _, t := range sb.Transfers {
+				cb.transfers = append(cb.transfers, transfer{
+					at:    time.Unix(0, t.At),
+					value: new(big.Int).SetBytes(t.Value),
+				})
+			}
+		}
+		cb.total(now)
+	}
+	return nil
+}
+
+// save ...
+// writes the held and approved messages, and the counted transfers, if
+// they changed
+func (p *policy) save() error {
+	if p == nil {
+		return nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.store()
+}
+
+func (p *policy) store() error {
+	if p.bk == nil || !p.dirty {
+		return nil
+	}
+	st := &policyState{}
+	if h := p.held; h != nil {
+		st.Held = &policyHeld{
+			Seq:     h.Seq,
+			Height:  h.Height,
+			Message: h.Message,
+			Reason:  h.Reason,
+			Halted:  h.Halted,
+			Since:   h.Since.UnixNano(),
+		}
+	}
+	for seq := range p.approved {
+		st.Approved = append(st.Approved, seq)
+	}
+	st.CountedSeq = p.countedSeq
+	for _, cb := range p.breakers {
+		sb := &policyBreaker{Coin: cb.coin}
+		for _, t := range cb.transfers {
+			sb.Transfers = append(sb.Transfers, &policyTransfer{At: t.at.UnixNano(), Value: t.value.Bytes()})
+		}
+		st.Breakers = append(st.Breakers, sb)
+	}
+	b, err := codec.RLP.MarshalToBytes(st)
+	if err != nil {
+		return err
+	}
+	if err := p.bk.Set([]byte(p.name), b); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// apply ...
+// returns the messages of "msg" preceding the first one the policy
+// doesn't let through, and that message, held, if any; "isNew" is false
+// if it was already held; call save to persist the change
+func (p *policy) apply(msg *chain.Message) (allowed *chain.Message, held *HeldMessage, isNew bool) {
+	if p == nil {
+		return msg, nil, false
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if first, _ := sequenceRange(msg.Receipts); first > 0 {
+		for seq := range p.approved {
+			if seq < first {
+				delete(p.approved, seq)
+				p.dirty = true
+			}
+		}
+	}
+	// transfers let through but not delivered yet
+	pending := make(map[string]*big.Int)
+	for i, receipt := range msg.Receipts {
+		for j, event := range receipt.Events {
+			d, err := btp.Decode(event.Message)
+			if reason, halt := p.check(event, d, err, pending); reason != "" {
+				if isNew = p.held == nil || p.held.Seq != event.Sequence; isNew {
+					p.dirty = true
+					p.held = &HeldMessage{
+						Seq:     event.Sequence,
+						Height:  receipt.Height,
+						Message: btpMessage(event),
+						Reason:  reason,
+						Halted:  halt,
+						Since:   time.Now(),
+					}
+				}
+				allowed = &chain.Message{From: msg.From, Receipts: msg.Receipts[:i:i]}
+				if j > 0 {
+					partial := *receipt
+					partial.Events = receipt.Events[:j:j]
+					allowed.Receipts = append(allowed.Receipts, &partial)
+				}
+				h := *p.held
+				return allowed, &h, isNew
+			}
+			if event.Sequence > p.countedSeq {
+				p.add(pending, d)
+			}
+		}
+	}
+	if p.held != nil {
+		p.held, p.dirty = nil, true
+	}
+	return msg, nil, false
+}
+
+// check ...
+// returns why the message "d" can't be relayed, and whether the relay
+// must be halted, or an empty reason; the circuit breakers count the
+// "pending" transfers preceding it as well as the delivered ones
+func (p *policy) check(event *chain.Event, d *btp.Decoded, err error, pending map[string]*big.Int) (reason string, halt bool) {
+	if p.approved[event.Sequence] {
+		return "", false
+	}
+	if err != nil {
+		if p.services != nil {
+			return fmt.Sprintf("undecodable message: %v", err), true
+		}
+		return "", false
+	}
+	if p.services != nil && !p.services[d.Svc] {
+		return fmt.Sprintf("unknown service %q", d.Svc), true
+	}
+	if d.BTS == nil || d.BTS.Type != btp.BTSRequestCoinTransfer || d.Sn.Sign() < 0 {
+		return "", false
+	}
+	if d.BTS.Transfer == nil {
+		if len(p.holdAbove) > 0 || len(p.breakers) > 0 {
+			return "undecodable transfer", false
+		}
+		return "", false
+	}
+	for _, a := range d.BTS.Transfer.Assets {
+		if limit, ok := p.holdAbove[a.Name]; ok && a.Value.Cmp(limit) > 0 {
+			return fmt.Sprintf("%s %v above %v", a.Name, a.Value, limit), false
+		}
+	}
+	if event.Sequence <= p.countedSeq {
+		return "", false
+	}
+	now := time.Now()
+	for _, cb := range p.breakers {
+		total := cb.total(now)
+		if v, ok := pending[cb.coin]; ok {
+			total.Add(total, v)
+		}
+		for _, a := range d.BTS.Transfer.Assets {
+			if a.Name == cb.coin {
+				total.Add(total, a.Value)
+			}
+		}
+		if total.Cmp(cb.limit) > 0 {
+			return fmt.Sprintf("circuit breaker: %s %v within %v above %v", cb.coin, total, cb.window, cb.limit), true
+		}
+	}
+	return "", false
+}
+
+// add ...
+// adds the value transferred by "d" to "values" by coin
+func (p *policy) add(values map[string]*big.Int, d *btp.Decoded) {
+	if d == nil || d.BTS == nil || d.BTS.Transfer == nil || d.Sn.Sign() < 0 {
+		return
+	}
+	for _, a := range d.BTS.Transfer.Assets {
+		if v, ok := values[a.Name]; ok {
+			v.Add(v, a.Value)
+		} else {
+			values[a.Name] = new(big.Int).Set(a.Value)
+		}
+	}
+}
+
+// delivered ...
+// adds the transfers of "pending" preceding "rest", confirmed delivered,
+// to the circuit breakers, once, and saves them
+func (p *policy) delivered(pending, rest []*chain.Receipt) error {
+	if p == nil || len(p.breakers) == 0 {
+		return nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	var next uint64 // first sequence not delivered, any if zero
+	if len(rest) > 0 {
+		next = rest[0].Events[0].Sequence
+	}
+	values := make(map[string]*big.Int)
+	for _, receipt := range pending {
+		for _, event := range receipt.Events {
+			if next > 0 && event.Sequence >= next {
+				break
+			}
+			if event.Sequence <= p.countedSeq {
+				continue
+			}
+			p.countedSeq, p.dirty = event.Sequence, true
+			if d, err := btp.Decode(event.Message); err == nil {
+				p.add(values, d)
+			}
+		}
+	}
+	now := time.Now()
+	for _, cb := range p.breakers {
+		if v, ok := values[cb.coin]; ok {
+			cb.transfers = append(cb.transfers, transfer{at: now, value: v})
+		}
+		cb.total(now)
+	}
+	return p.store()
+}
+
+// total ...
+// drops the transfers older than the window and returns the sum of the others
+func (cb *circuitBreaker) total(now time.Time) *big.Int {
+	i := 0
+	for i < len(cb.transfers) && now.Sub(cb.transfers[i].at) > cb.window {
+		i++
+	}
+	cb.transfers = cb.transfers[i:]
+	total := new(big.Int)
+	for _, t := range cb.transfers {
+		total.Add(total, t.value)
+	}
+	return total
+}
+
+// release ...
+// approves the held message "seq", and returns it
+func (p *policy) release(seq uint64) (*HeldMessage, error) {
+	if p == nil {
+		return nil, ErrNoHeldMessage
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.held == nil {
+		return nil, ErrNoHeldMessage
+	}
+	if p.held.Seq != seq {
+		return nil, fmt.Errorf("held message is seq %d, not %d", p.held.Seq, seq)
+	}
+	held := p.held
+	p.approved[seq], p.held, p.dirty = true, nil, true
+	if err := p.store(); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+func (p *policy) heldMessage() *HeldMessage {
+	if p == nil {
+		return nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.held == nil {
+		return nil
+	}
+	h := *p.held
+	return &h
+}
+
+// remaining ...
+// returns the receipts of "pending" left after the delivery of the part
+// of "relayed" not in "rest", keeping those the policy held back
+func remaining(pending []*chain.Receipt, relayed, rest *chain.Message) []*chain.Receipt {
+	_, seq := sequenceRange(relayed.Receipts)
+	if len(rest.Receipts) > 0 {
+		seq = rest.Receipts[0].Events[0].Sequence - 1
+	}
+	for i, receipt := range pending {
+		for j, event := range receipt.Events {
+			if event.Sequence > seq {
+				if j == 0 {
+					return pending[i:]
+				}
+				partial := *receipt
+				partial.Events = receipt.Events[j:]
+				return append([]*chain.Receipt{&partial}, pending[i+1:]...)
+			}
+		}
+	}
+	return nil
+}
+
+// hold ...
+// reports the message newly held by the policy, and pauses the relay
+// if it must be halted
+func (r *relay) hold(held *HeldMessage) {
+	l := r.log.WithFields(log.Fields{
+		"seq":     held.Seq,
+		"height":  held.Height,
+		"message": held.Message,
+		"reason":  held.Reason,
+	})
+	if held.Halted {
+		r.ctl.pause(true)
+		l.Error("policy: relay halted, release the message and resume the relay")
+	} else {
+		l.Warn("policy: message held, release it to resume relaying")
+	}
+	relayMetrics.heldSeq.set(r.cfg.Name, float64(held.Seq))
+	r.journal(JournalHeld, JournalEntry{
+		SeqBegin: held.Seq,
+		SeqEnd:   held.Seq,
+		Messages: []string{held.Message},
+		Error:    held.Reason,
+	})
+}
+
+// loadPolicy ...
+// restores the message held by a previous run, and halts the relay again
+// if it was halted by it
+func (r *relay) loadPolicy() error {
+	if err := r.pol.load(); err != nil {
+		return err
+	}
+	held := r.pol.heldMessage()
+	if held == nil {
+		return nil
+	}
+	if held.Halted {
+		r.ctl.pause(true)
+	}
+	relayMetrics.heldSeq.set(r.cfg.Name, float64(held.Seq))
+	r.log.WithFields(log.Fields{
+		"seq":     held.Seq,
+		"message": held.Message,
+		"reason":  held.Reason,
+	}).Warn("policy: message still held, release it to resume relaying")
+	return nil
+}
+
+// release ...
+// lets the held message "seq" through, and resumes the relay if it was halted by it
+func (r *relay) release(seq uint64) error {
+	held, err := r.pol.release(seq)
+	if err != nil {
+		return err
+	}
+	if held.Halted {
+		r.ctl.pause(false)
+	}
+	relayMetrics.heldSeq.set(r.cfg.Name, 0)
+	r.journal(JournalReleased, JournalEntry{
+		SeqBegin: held.Seq,
+		SeqEnd:   held.Seq,
+		Messages: []string{held.Message},
+	})
+	r.log.WithFields(log.Fields{"seq": seq, "message": held.Message}).Info("policy: message released")
+	return nil
+}
@@ -0,0 +1,199 @@
+package relay
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/btp"
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func btpEvent(t *testing.T, seq uint64, svc string, coin string, value int64) *chain.Event {
+	type asset struct {
+		Name  string
+		Value []byte
+	}
+	type transfer struct {
+		From, To string
+		Assets   []*asset
+	}
+	type payload struct {
+		Type []byte
+		Data []byte
+	}
+	type message struct {
+		Src, Dst, Svc string
+		Sn, Payload   []byte
+	}
+	data, err := codec.RLP.MarshalToBytes(&transfer{
+		From: "hx01", To: "0x02", Assets: []*asset{{Name: coin, Value: big.NewInt(value).Bytes()}}})
+	require.NoError(t, err)
+	p, err := codec.RLP.MarshalToBytes(&payload{Type: []byte{btp.BTSRequestCoinTransfer}, Data: data})
+	require.NoError(t, err)
+	b, err := codec.RLP.MarshalToBytes(&message{Src: "src", Dst: "dst", Svc: svc, Sn: []byte{byte(seq)}, Payload: p})
+	require.NoError(t, err)
+	return &chain.Event{Sequence: seq, Message: b}
+}
+
+func TestPolicyHold(t *testing.T) {
+	p, err := newPolicy("i2b", &PolicyConfig{HoldAbove: map[string]string{"ICX": "100"}})
+	require.NoError(t, err)
+	msg := &chain.Message{Receipts: []*chain.Receipt{
+		{Height: 10, Events: []*chain.Event{btpEvent(t, 1, "bts", "ICX", 10), btpEvent(t, 2, "bts", "ICX", 1000)}},
+		{Height: 11, Events: []*chain.Event{btpEvent(t, 3, "bts", "ICX", 10)}},
+	}}
+
+	allowed, held, isNew := p.apply(msg)
+	require.NotNil(t, held)
+	assert.True(t, isNew)
+	assert.False(t, held.Halted)
+	assert.Equal(t, uint64(2), held.Seq)
+	require.Len(t, allowed.Receipts, 1)
+	assert.Len(t, allowed.Receipts[0].Events, 1)
+	assert.Len(t, msg.Receipts[0].Events, 2)
+
+	_, _, isNew = p.apply(msg)
+	assert.False(t, isNew)
+
+	// the receipt split by the policy is kept from the held message
+	rest := remaining(msg.Receipts, allowed, &chain.Message{})
+	require.Len(t, rest, 2)
+	assert.Equal(t, uint64(2), rest[0].Events[0].Sequence)
+
+	_, err = p.release(3)
+	assert.Error(t, err)
+	_, err = p.release(2)
+	require.NoError(t, err)
+	allowed, held, _ = p.apply(&chain.Message{Receipts: rest})
+	assert.Nil(t, held)
+	assert.Len(t, allowed.Receipts, 2)
+	assert.Nil(t, p.heldMessage())
+}
+
+func TestPolicyHalt(t *testing.T) {
+	p, err := newPolicy("i2b", &PolicyConfig{
+		Services:        []string{"bmc", "bts"},
+		CircuitBreakers: []*CircuitBreakerConfig{{Coin: "ICX", Limit: "100"}},
+	})
+	require.NoError(t, err)
+
+	_, held, _ := p.apply(&chain.Message{Receipts: []*chain.Receipt{
+		{Height: 10, Events: []*chain.Event{btpEvent(t, 1, "xcall", "ICX", 10)}},
+	}})
+	require.NotNil(t, held)
+	assert.True(t, held.Halted)
+	assert.Contains(t, held.Reason, "unknown service")
+	_, err = p.release(1)
+	require.NoError(t, err)
+
+	msg := &chain.Message{Receipts: []*chain.Receipt{
+		{Height: 10, Events: []*chain.Event{btpEvent(t, 1, "xcall", "ICX", 10)}},
+		{Height: 11, Events: []*chain.Event{btpEvent(t, 2, "bts", "ICX", 60)}},
+		{Height: 12, Events: []*chain.Event{btpEvent(t, 3, "bts", "ICX", 60)}},
+	}}
+	allowed, held, _ := p.apply(msg)
+	require.NotNil(t, held)
+	assert.True(t, held.Halted)
+	assert.Equal(t, uint64(3), held.Seq)
+	assert.Contains(t, held.Reason, "circuit breaker")
+	assert.Len(t, allowed.Receipts, 2)
+
+	// transfers are counted once delivered
+	assert.Equal(t, int64(0), p.breakers[0].total(time.Now()).Int64())
+	p.delivered(msg.Receipts, msg.Receipts[2:])
+	p.delivered(msg.Receipts[1:], msg.Receipts[2:])
+	assert.Equal(t, int64(60), p.breakers[0].total(time.Now()).Int64())
+	_, held, _ = p.apply(&chain.Message{Receipts: msg.Receipts[2:]})
+	assert.Equal(t, uint64(3), held.Seq)
+
+	_, err = newPolicy("i2b", &PolicyConfig{HoldAbove: map[string]string{"ICX": "1e18"}})
+	assert.Error(t, err)
+}
+
+func TestPolicyPersist(t *testing.T) {
+	bk, err := db.NewMapDB().GetBucket(PolicyBucket)
+	require.NoError(t, err)
+	cfg := &PolicyConfig{HoldAbove: map[string]string{"ICX": "100"}}
+	p, err := newPolicy("i2b", cfg)
+	require.NoError(t, err)
+	p.bk = bk
+	require.NoError(t, p.load())
+
+	msg := &chain.Message{Receipts: []*chain.Receipt{
+		{Height: 10, Events: []*chain.Event{btpEvent(t, 1, "bts", "ICX", 1000)}},
+		{Height: 11, Events: []*chain.Event{btpEvent(t, 2, "bts", "ICX", 1000)}},
+	}}
+	_, held, _ := p.apply(msg)
+	require.NotNil(t, held)
+	require.NoError(t, p.save())
+
+	// the held message survives a restart
+	p, err = newPolicy("i2b", cfg)
+	require.NoError(t, err)
+	p.bk = bk
+	require.NoError(t, p.load())
+	restored := p.heldMessage()
+	require.NotNil(t, restored)
+	assert.Equal(t, held.Seq, restored.Seq)
+	assert.Equal(t, held.Reason, restored.Reason)
+	assert.True(t, held.Since.Equal(restored.Since))
+	_, _, isNew := p.apply(msg)
+	assert.False(t, isNew)
+
+	// so does its approval
+	_, err = p.release(1)
+	require.NoError(t, err)
+	p, err = newPolicy("i2b", cfg)
+	require.NoError(t, err)
+	p.bk = bk
+	require.NoError(t, p.load())
+	assert.Nil(t, p.heldMessage())
+	_, held, _ = p.apply(msg)
+	require.NotNil(t, held)
+	assert.Equal(t, uint64(2), held.Seq)
+}
+
+func TestPolicyPersistBreakers(t *testing.T) {
+	bk, err := db.NewMapDB().GetBucket(PolicyBucket)
+	require.NoError(t, err)
+	cfg := &PolicyConfig{CircuitBreakers: []*CircuitBreakerConfig{{Coin: "ICX", Limit: "100"}}}
+	p, err := newPolicy("i2b", cfg)
+	require.NoError(t, err)
+	p.bk = bk
+	require.NoError(t, p.load())
+
+	msg := &chain.Message{Receipts: []*chain.Receipt{
+		{Height: 10, Events: []*chain.Event{btpEvent(t, 1, "bts", "ICX", 45)}},
+		{Height: 11, Events: []*chain.Event{btpEvent(t, 2, "bts", "ICX", 45)}},
+	}}
+	_, held, _ := p.apply(msg)
+	require.Nil(t, held)
+	require.NoError(t, p.delivered(msg.Receipts, nil))
+
+	// the transfers within the window survive a restart
+	p, err = newPolicy("i2b", cfg)
+	require.NoError(t, err)
+	p.bk = bk
+	require.NoError(t, p.load())
+	assert.Equal(t, uint64(2), p.countedSeq)
+	assert.Equal(t, int64(90), p.breakers[0].total(time.Now()).Int64())
+	_, held, _ = p.apply(&chain.Message{Receipts: []*chain.Receipt{
+		{Height: 12, Events: []*chain.Event{btpEvent(t, 3, "bts", "ICX", 20)}},
+	}})
+	require.NotNil(t, held)
+	assert.True(t, held.Halted)
+	assert.Contains(t, held.Reason, "circuit breaker")
+
+	// and are dropped once out of it
+	p, err = newPolicy("i2b", &PolicyConfig{CircuitBreakers: []*CircuitBreakerConfig{{Coin: "ICX", Limit: "100", Window: 1}}})
+	require.NoError(t, err)
+	p.bk = bk
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, p.load())
+	assert.Empty(t, p.breakers[0].transfers)
+}
@@ -0,0 +1,226 @@
+package relay
+
+import (
+	"encoding/binary"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/codec"
+	"github.com/icon-project/icon-bridge/common/db"
+)
+
+const (
+	// QueueBucket maps the spilled queue of a relay from relay name,
+	// and its segments from relay name and segment index
+	QueueBucket db.BucketID = "relay_queue_"
+
+	defaultQueueMemoryLimit = 1000
+	defaultQueueSegmentSize = 100
+)
+
+// QueueConfig ...
+// bounds the receipts pending delivery kept in memory, the rest spill to
+// the relay db; the queue is unbounded in memory if the db isn't configured
+type QueueConfig struct {
+	MemoryLimit uint `json:"memory_limit"` // receipts in memory
+	SegmentSize uint `json:"segment_size"` // receipts per segment on disk
+}
+
+type queueMeta struct {
+	First    uint64 // index of the oldest segment
+	Next     uint64 // index of the next segment
+	LastSize uint64 // receipts in the newest segment
+	Receipts uint64 // receipts in all the segments
+	Seq      uint64 // last event sequence spilled
+}
+
+type queueSegment struct {
+	Receipts []*chain.Receipt
+}
+
+// queue ...
+// spills the receipts pending delivery beyond the memory limit to a log of
+// segments in the relay db, which are loaded back in order as the receipts
+// in memory are delivered; the receipts in memory are the relay's srcMsg
+// and persisted by its checkpoint
+type queue struct {
+	name     string
+	bk       db.Bucket // nil if the relay db isn't configured
+	memLimit int
+	segSize  int
+	meta     queueMeta
+}
+
+func newQueue(name string, cfg *QueueConfig) queue {
+	q := queue{
+		name:     name,
+		memLimit: defaultQueueMemoryLimit,
+		segSize:  defaultQueueSegmentSize,
+	}
+	if cfg != nil && cfg.MemoryLimit > 0 {
+		q.memLimit = int(cfg.MemoryLimit)
+	}
+	if cfg != nil && cfg.SegmentSize > 0 {
+		q.segSize = int(cfg.SegmentSize)
+	}
+	return q
+}
+
+func (q *queue) key(index uint64) []byte {
+	k := make([]byte, len(q.name)+9)
+	copy(k, q.name)
+	k[len(q.name)] = '/'
+	binary.BigEndian.PutUint64(k[len(q.name)+1:], index)
+	return k
+}
+
+// len ...
+// returns the number of receipts spilled to disk
+func (q *queue) len() int {
+	return int(q.meta.Receipts)
+}
+
+// load ...
+// reads the state of the spilled queue saved by a previous run
+func (q *queue) load() error {
+	q.meta = queueMeta{}
+	if q.bk == nil {
+		return nil
+	}
+	b, err := q.bk.Get([]byte(q.name))
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	_, err = codec.RLP.UnmarshalFromBytes(b, &q.meta)
+	return err
+}
+
+func (q *queue) saveMeta() error {
+	if q.meta.First == q.meta.Next {
+		q.meta = queueMeta{Seq: q.meta.Seq}
+	}
+	b, err := codec.RLP.MarshalToBytes(&q.meta)
+	if err != nil {
+		return err
+	}
+	return q.bk.Set([]byte(q.name), b)
+}
+
+func (q *queue) readSegment(index uint64) (*queueSegment, error) {
+	seg := &queueSegment{}
+	b, err := q.bk.Get(q.key(index))
+	if err != nil || len(b) == 0 {
+		return seg, err
+	}
+	_, err = codec.RLP.UnmarshalFromBytes(b, seg)
+	return seg, err
+}
+
+func (q *queue) writeSegment(index uint64, seg *queueSegment) error {
+	b, err := codec.RLP.MarshalToBytes(seg)
+	if err != nil {
+		return err
+	}
+	return q.bk.Set(q.key(index), b)
+}
+
+// clear ...
+// drops the spilled receipts
+func (q *queue) clear() error {
+	if q.bk == nil {
+		return nil
+	}
+	for i := q.meta.First; i < q.meta.Next; i++ {
+		if err := q.bk.Delete(q.key(i)); err != nil {
+			return err
+		}
+	}
+	q.meta = queueMeta{}
+	return q.bk.Delete([]byte(q.name))
+}
+
+// push ...
+// appends "receipts" to the queue whose receipts in memory are "head",
+// and returns the new head
+func (q *queue) push(head, receipts []*chain.Receipt) ([]*chain.Receipt, error) {
+	if q.bk == nil {
+		return append(head, receipts...), nil
+	}
+	if q.meta.Receipts == 0 {
+		n := q.memLimit - len(head)
+		if n > len(receipts) {
+			n = len(receipts)
+		}
+		if n > 0 {
+			head = append(head, receipts[:n]...)
+			receipts = receipts[n:]
+		}
+	}
+	// skip the receipts spilled before a restart, and received again
+	// because the checkpoint wasn't saved yet
+	for len(receipts) > 0 && lastSequence(receipts[0]) <= q.meta.Seq {
+		receipts = receipts[1:]
+	}
+	if len(receipts) == 0 {
+		return head, nil
+	}
+	return head, q.spill(receipts)
+}
+
+func (q *queue) spill(receipts []*chain.Receipt) error {
+	for len(receipts) > 0 {
+		index, seg := q.meta.Next, &queueSegment{}
+		if q.meta.Next > q.meta.First && int(q.meta.LastSize) < q.segSize {
+			var err error
+			index = q.meta.Next - 1
+			if seg, err = q.readSegment(index); err != nil {
+				return err
+			}
+		}
+		n := q.segSize - len(seg.Receipts)
+		if n > len(receipts) {
+			n = len(receipts)
+		}
+		seg.Receipts = append(seg.Receipts, receipts[:n]...)
+		if err := q.writeSegment(index, seg); err != nil {
+			return err
+		}
+		q.meta.Next = index + 1
+		q.meta.LastSize = uint64(len(seg.Receipts))
+		q.meta.Receipts += uint64(n)
+		q.meta.Seq = lastSequence(receipts[n-1])
+		receipts = receipts[n:]
+	}
+	return q.saveMeta()
+}
+
+// refill ...
+// moves the oldest segments to "head" until it reaches the memory limit,
+// and returns the new head; segments delivered up to "rxSeq" are dropped
+func (q *queue) refill(head []*chain.Receipt, rxSeq uint64) ([]*chain.Receipt, error) {
+	if q.bk == nil || q.meta.Receipts == 0 || len(head) >= q.memLimit {
+		return head, nil
+	}
+	for len(head) < q.memLimit && q.meta.First < q.meta.Next {
+		seg, err := q.readSegment(q.meta.First)
+		if err != nil {
+			return head, err
+		}
+		if err := q.bk.Delete(q.key(q.meta.First)); err != nil {
+			return head, err
+		}
+		q.meta.First++
+		q.meta.Receipts -= uint64(len(seg.Receipts))
+		if n := len(seg.Receipts); n > 0 && lastSequence(seg.Receipts[n-1]) <= rxSeq {
+			continue
+		}
+		head = append(head, seg.Receipts...)
+	}
+	return head, q.saveMeta()
+}
+
+func lastSequence(receipt *chain.Receipt) uint64 {
+	if len(receipt.Events) == 0 {
+		return 0
+	}
+	return receipt.Events[len(receipt.Events)-1].Sequence
+}
@@ -0,0 +1,68 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	bk, err := db.NewMapDB().GetBucket(QueueBucket)
+	require.NoError(t, err)
+	receipts := func(from, to uint64) (rs []*chain.Receipt) {
+		for seq := from; seq <= to; seq++ {
+			rs = append(rs, &chain.Receipt{Height: seq, Events: []*chain.Event{{Sequence: seq}}})
+		}
+		return rs
+	}
+	seqs := func(rs []*chain.Receipt) (s []uint64) {
+		for _, r := range rs {
+			s = append(s, lastSequence(r))
+		}
+		return s
+	}
+
+	q := newQueue("i2b", &QueueConfig{MemoryLimit: 3, SegmentSize: 2})
+	q.bk = bk
+	require.NoError(t, q.load())
+
+	head, err := q.push(nil, receipts(1, 2))
+	require.NoError(t, err)
+	head, err = q.push(head, receipts(3, 7))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, seqs(head))
+	assert.Equal(t, 4, q.len())
+	assert.Equal(t, queueMeta{First: 0, Next: 2, LastSize: 2, Receipts: 4, Seq: 7}, q.meta)
+
+	// the spilled queue survives a restart, and duplicates are skipped
+	q = newQueue("i2b", &QueueConfig{MemoryLimit: 3, SegmentSize: 2})
+	q.bk = bk
+	require.NoError(t, q.load())
+	assert.Equal(t, 4, q.len())
+	head, err = q.push(head, receipts(7, 8))
+	require.NoError(t, err)
+	assert.Equal(t, 5, q.len())
+
+	// segments are loaded back as the head is delivered
+	head, err = q.refill(head[2:], 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(head))
+	assert.Equal(t, 3, q.len())
+
+	// segments delivered by another relayer are trimmed
+	head, err = q.refill(nil, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{8}, seqs(head))
+	assert.Equal(t, 0, q.len())
+
+	head, err = q.push(head, receipts(9, 12))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{8, 9, 10}, seqs(head))
+	require.NoError(t, q.clear())
+	assert.Equal(t, 0, q.len())
+	require.NoError(t, q.load())
+	assert.Equal(t, queueMeta{}, q.meta)
+}
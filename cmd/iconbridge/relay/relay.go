@@ -3,7 +3,6 @@ package relay
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
@@ -12,26 +11,48 @@ import (
 )
 
 const (
-	relayTickerInterval                  = 5 * time.Second
-	relayBalanceCheckInterval            = 60 * time.Second
-	relayTriggerReceiptsCount            = 20
-	relayTxSendWaitInterval              = time.Second / 2
-	relayTxReceiptWaitInterval           = time.Second
-	relayInsufficientBalanceWaitInterval = 30 * time.Second
-	retryWarnThreshold                   = 15
+	retryWarnThreshold = 15
 )
 
 type Relay interface {
 	Start(ctx context.Context) (err error)
 }
 
-func NewRelay(cfg *RelayConfig, src chain.Receiver, dst chain.Sender, log log.Logger) (Relay, error) {
+func NewRelay(cfg *RelayConfig, src chain.Receiver, dst chain.Sender, cps CheckpointStore, log log.Logger) (Relay, error) {
+	return newRelay(cfg, src, dst, cps, log)
+}
+
+func newRelay(cfg *RelayConfig, src chain.Receiver, dst chain.Sender, cps CheckpointStore, log log.Logger) (*relay, error) {
+	loop, err := newLoopOptions(cfg.Loop, cfg.Dst.Address.BlockChain())
+	if err != nil {
+		return nil, fmt.Errorf("loop config: %v", err)
+	}
+	pol, err := newPolicy(cfg.Name, cfg.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("policy config: %v", err)
+	}
+	rw, err := newRunway(cfg.Runway)
+	if err != nil {
+		return nil, fmt.Errorf("runway config: %v", err)
+	}
 	r := &relay{
 		cfg: cfg,
 		log: log,
 		src: src,
 		dst: dst,
+		cps: cps,
+		pol: pol,
+		rw:  rw,
+	}
+	if _, ok := dst.(chain.PipelinedSender); !ok && loop.pipeline > 1 {
+		log.Warnf("pipeline=%d: destination doesn't support pipelined relay txs, disabled", loop.pipeline)
+		loop.pipeline = 1
 	}
+	r.loop = loop
+	r.ctl.shadow = cfg.Shadow
+	r.rt.cfg = cfg.Rotation
+	r.rp = newRestartPolicy(cfg.Restart)
+	r.q = newQueue(cfg.Name, cfg.Queue)
 	return r, nil
 }
 
@@ -40,6 +61,38 @@ type relay struct {
 	log log.Logger
 	src chain.Receiver
 	dst chain.Sender
+	cps CheckpointStore
+	cp  Checkpoint
+	bl  batchLimit
+
+	cpSavedAt time.Time // last time the checkpoint was saved
+
+	loop   loopOptions
+	state  relayState
+	ctl    relayControl
+	shadow shadowState
+	rt     rotation
+	rp     restartPolicy
+	q      queue
+	js     JournalStore // nil if the relay db isn't configured
+	pol    *policy      // nil if no policy is configured
+	rw     *runway
+	ev     *eventBus // nil if events aren't published
+}
+
+// close ...
+// releases the receiver and the sender of a relay that's discarded
+func (r *relay) close() {
+	if r.src != nil {
+		if err := r.src.Close(); err != nil {
+			r.log.WithFields(log.Fields{"error": err}).Warn("src.Close: failed")
+		}
+	}
+	if r.dst != nil {
+		if err := r.dst.Close(); err != nil {
+			r.log.WithFields(log.Fields{"error": err}).Warn("dst.Close: failed")
+		}
+	}
 }
 
 func (r *relay) rxHeight(linkRxHeight uint64) uint64 {
@@ -50,6 +103,88 @@ func (r *relay) rxHeight(linkRxHeight uint64) uint64 {
 	return height
 }
 
+// linkSubscribeOptions ...
+// returns the options to subscribe from the last message delivered to
+// the destination, and resets the checkpoint accordingly
+func (r *relay) linkSubscribeOptions(link *chain.BMCLinkStatus) chain.SubscribeOptions {
+	opts := chain.SubscribeOptions{
+		Seq:    link.RxSeq,
+		Height: r.rxHeight(link.RxHeight),
+	}
+	r.cp = Checkpoint{Seq: opts.Seq, RxSeq: link.RxSeq}
+	if opts.Height > 0 {
+		r.cp.Height = opts.Height - 1
+	}
+	return opts
+}
+
+// segmentOptions ...
+// limits the relay message to the adaptive batch size and to the
+// maximum aggregation accepted by the destination BMC
+func (r *relay) segmentOptions(link *chain.BMCLinkStatus) chain.SegmentOptions {
+	opts := r.bl.options()
+	if maxAgg := uint64(link.MaxAggregation); maxAgg > 0 &&
+		(opts.MaxReceipts == 0 || opts.MaxReceipts > maxAgg) {
+		opts.MaxReceipts = maxAgg
+	}
+	return opts
+}
+
+// canSubmit ...
+// returns whether the relay may send a relay tx according to the
+// relayer rotation, always true if rotation isn't configured or enabled
+func (r *relay) canSubmit(link *chain.BMCLinkStatus, pending bool) bool {
+	if r.rt.cfg == nil || link.RotateTerm == 0 {
+		return true
+	}
+	turn, delayed := r.rt.check(link, pending)
+	if !turn && pending {
+		l := r.log.WithFields(log.Fields{
+			"active":        r.rt.activeIndex(link),
+			"index":         r.rt.cfg.Index,
+			"currentHeight": link.CurrentHeight,
+			"pendingHeight": r.rt.pendingHeight,
+		})
+		if !delayed {
+			l.Debug("rotation: not our turn")
+			return false
+		}
+		l.Warn("rotation: active relayer exceeded delay limit, relaying")
+	}
+	return true
+}
+
+// loadCheckpoint ...
+// returns the last saved checkpoint, or nil if it's missing or unusable
+func (r *relay) loadCheckpoint() *Checkpoint {
+	if r.cps == nil {
+		return nil
+	}
+	cp, err := r.cps.Load(r.cfg.Name)
+	if err != nil {
+		r.log.WithFields(log.Fields{"error": err}).Warn("failed to load checkpoint")
+		return nil
+	}
+	return cp
+}
+
+// saveCheckpoint ...
+// saves the checkpoint with the receipts pending delivery, at most every
+// defaultCheckpointInterval unless "force"
+func (r *relay) saveCheckpoint(receipts []*chain.Receipt, force bool) {
+	if r.cps == nil {
+		return
+	}
+	if !force && time.Since(r.cpSavedAt) < defaultCheckpointInterval {
+		return
+	}
+	r.cpSavedAt = time.Now()
+	r.cp.Receipts = receipts
+	if err := r.cps.Save(r.cfg.Name, &r.cp); err != nil {
+		r.log.WithFields(log.Fields{"error": err}).Warn("failed to save checkpoint")
+	}
+}
+
 func (r *relay) Start(ctx context.Context) error {
 
 	link, err := r.dst.Status(ctx)
@@ -61,21 +196,58 @@ func (r *relay) Start(ctx context.Context) error {
 		"rxHeight":      link.RxHeight,
 		"currentHeight": link.CurrentHeight,
 	}).Info("link status")
+	relayMetrics.dstRxSeq.set(r.cfg.Name, float64(link.RxSeq))
+	relayMetrics.dstRxHeight.set(r.cfg.Name, float64(link.RxHeight))
+	if r.state.setLink(link) {
+		r.event(EventLink, &Event{Link: link})
+	}
 
-	srcMsgCh := make(chan *chain.Message)
-	srcErrCh, err := r.src.Subscribe(ctx,
-		srcMsgCh,
-		chain.SubscribeOptions{
-			Seq:    link.RxSeq,
-			Height: r.rxHeight(link.RxHeight),
-		})
-	if err != nil {
-		return err
+	// txs left in flight by a previous run may never be confirmed
+	if ps, ok := r.dst.(chain.PipelinedSender); ok {
+		ps.ResetPipeline()
 	}
 
 	srcMsg := &chain.Message{
 		From: r.cfg.Src.Address,
 	}
+	// backlog ...
+	// returns the number of receipts pending delivery, including spilled ones
+	backlog := func() int {
+		return len(srcMsg.Receipts) + r.q.len()
+	}
+
+	if err := r.q.load(); err != nil {
+		return err
+	}
+	if err := r.loadPolicy(); err != nil {
+		return err
+	}
+
+	// resume from the newer of the checkpoint and the link status
+	opts := r.linkSubscribeOptions(link)
+	if cp := r.loadCheckpoint(); cp != nil && cp.Seq >= opts.Seq && cp.Height >= opts.Height {
+		r.log.WithFields(log.Fields{
+			"height":   cp.Height,
+			"seq":      cp.Seq,
+			"receipts": len(cp.Receipts),
+		}).Info("resume from checkpoint")
+		opts.Seq, opts.Height = cp.Seq, cp.Height+1
+		srcMsg.Receipts = cp.Receipts
+		r.cp = *cp
+		r.cp.RxSeq = link.RxSeq
+		r.state.setBacklog(cp.Height, backlog(), false)
+	} else if err := r.q.clear(); err != nil {
+		return err
+	}
+	defer func() { r.saveCheckpoint(srcMsg.Receipts, true) }()
+
+	sub, err := r.subscribe(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() { sub.close() }()
+	r.state.setSubscribed(true)
+	defer r.state.setSubscribed(false)
 
 	filterSrcMsg := func(rxHeight, rxSeq uint64) (missingRxSeq uint64) {
 		receipts := srcMsg.Receipts[:0]
@@ -102,28 +274,87 @@ func (r *relay) Start(ctx context.Context) error {
 		return 0
 	}
 
+	// resync ...
+	// drops the messages already delivered to the destination, e.g. by
+	// another relayer, and if some sequences are missing, resubscribes from
+	// the last delivered one instead of restarting the relay
+	resync := func(link *chain.BMCLinkStatus) error {
+		missing := filterSrcMsg(link.RxHeight, link.RxSeq)
+		if missing == 0 {
+			return nil
+		}
+		opts := r.linkSubscribeOptions(link)
+		r.log.WithFields(log.Fields{
+			"rxSeq":  missing,
+			"seq":    opts.Seq,
+			"height": opts.Height,
+		}).Warn("missing event sequence: resubscribing")
+		sub.close()
+		srcMsg.Receipts = nil
+		if err := r.q.clear(); err != nil {
+			return err
+		}
+		r.saveCheckpoint(nil, true)
+		r.state.setBacklog(0, 0, false)
+		sub, err = r.subscribe(ctx, opts)
+		return err
+	}
+
 	relayCh := make(chan struct{}, 1)
-	relayTicker := time.NewTicker(relayTickerInterval)
+	relayTicker := time.NewTicker(r.loop.tickerInterval)
 	defer relayTicker.Stop()
 	relaySignal := func() {
 		select {
 		case relayCh <- struct{}{}:
 		default:
 		}
-		relayTicker.Reset(relayTickerInterval)
+		relayTicker.Reset(r.loop.tickerInterval)
 		r.log.Debug("relaySignal")
 	}
 
 	txBlockHeight := link.CurrentHeight
 
-	relayBalanceCheckTicker := time.NewTicker(relayBalanceCheckInterval)
+	relayBalanceCheckTicker := time.NewTicker(r.loop.balanceCheckInterval)
 	defer relayBalanceCheckTicker.Stop()
 
+	// relayMsg ...
+	// is srcMsg, or the part of it let through by the policy
+	var relayMsg *chain.Message
+
+	// delivered ...
+	// drops the messages of a confirmed relay tx from srcMsg
+	delivered := func(newMsg *chain.Message, blockHeight uint64, sentAt time.Time) {
+		if relayMsg != nil && relayMsg != srcMsg {
+			newMsg = &chain.Message{Receipts: remaining(srcMsg.Receipts, relayMsg, newMsg)}
+		}
+		newMsg.From = srcMsg.From
+		if err := r.pol.delivered(srcMsg.Receipts, newMsg.Receipts); err != nil {
+			r.log.WithFields(log.Fields{"error": err}).Warn("policy: failed to save the delivered transfers")
+		}
+		srcMsg = newMsg
+		txBlockHeight = blockHeight
+		r.bl.increase()
+		r.cp.RxSeq = r.cp.Seq
+		if len(srcMsg.Receipts) > 0 {
+			r.cp.RxSeq = srcMsg.Receipts[0].Events[0].Sequence - 1
+		}
+		r.saveCheckpoint(srcMsg.Receipts, false)
+		relayMetrics.txReceiptLatency.observe(r.cfg.Name, time.Since(sentAt))
+		relayMetrics.pendingReceipts.set(r.cfg.Name, float64(backlog()))
+		r.state.setBacklog(0, backlog(), true)
+	}
+
+	drainCh := r.ctl.drained()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
+		case <-drainCh:
+			r.saveCheckpoint(srcMsg.Receipts, true)
+			return errRelayDrained
+
 		case <-relayTicker.C:
 			relaySignal()
 
@@ -135,17 +366,37 @@ func (r *relay) Start(ctx context.Context) error {
 				l := r.log.WithFields(log.Fields{"balance": bal, "threshold": thres})
 				if err != nil {
 					l.Error("failed to fetch relay wallet balance")
-				} else if bal.Cmp(thres) <= 0 {
+					return
+				}
+				relayMetrics.balance.set(r.cfg.Name, bigToFloat(bal))
+				relayMetrics.balanceThreshold.set(r.cfg.Name, bigToFloat(thres))
+				if bal.Cmp(thres) <= 0 {
 					l.Warn("relay wallet balance below threshold")
 				}
+				belowFloor := r.rw.isBelowFloor()
+				left, crossed := r.rw.update(bal, time.Now())
+				relayMetrics.runway.set(r.cfg.Name, left.Seconds())
+				if crossed > 0 {
+					l.WithFields(log.Fields{
+						"runway":    left.Truncate(time.Minute),
+						"threshold": crossed,
+					}).Warn("relay wallet runway below threshold")
+				}
+				switch {
+				case !belowFloor && r.rw.isBelowFloor():
+					l.WithFields(log.Fields{"floor": r.rw.floor}).Error("relay wallet balance below floor: sending paused")
+				case belowFloor && !r.rw.isBelowFloor():
+					l.WithFields(log.Fields{"floor": r.rw.floor}).Info("relay wallet balance above floor: sending resumed")
+				}
 			}()
 
-		case err := <-srcErrCh:
+		case err := <-sub.errCh:
 			return err
 
-		case msg := <-srcMsgCh:
+		case msg := <-sub.msgCh:
 
 			var seqBegin, seqEnd uint64
+			var force bool
 			receipts := msg.Receipts[:0]
 			for _, receipt := range msg.Receipts {
 				if len(receipt.Events) > 0 {
@@ -161,14 +412,58 @@ func (r *relay) Start(ctx context.Context) error {
 			if len(msg.Receipts) > 0 {
 				r.log.WithFields(log.Fields{
 					"seq": []uint64{seqBegin, seqEnd}}).Debug("srcMsg added")
-				srcMsg.Receipts = append(srcMsg.Receipts, msg.Receipts...)
-				if len(srcMsg.Receipts) > relayTriggerReceiptsCount {
-					relaySignal()
+				if r.log.GetLevel() >= log.DebugLevel {
+					for _, receipt := range msg.Receipts {
+						for _, event := range receipt.Events {
+							r.log.WithFields(log.Fields{
+								"seq":     event.Sequence,
+								"height":  receipt.Height,
+								"message": btpMessage(event),
+							}).Debug("btp message")
+						}
+					}
 				}
+				if r.ev.listening() {
+					r.event(EventReceipts, &Event{JournalEntry: JournalEntry{
+						SeqBegin: seqBegin,
+						SeqEnd:   seqEnd,
+						Receipts: len(msg.Receipts),
+						Height:   msg.Receipts[len(msg.Receipts)-1].Height,
+						Messages: btpMessages(msg.Receipts),
+					}})
+				}
+				spilled := r.q.len()
+				if srcMsg.Receipts, err = r.q.push(srcMsg.Receipts, msg.Receipts); err != nil {
+					return err
+				}
+				// the receipts in memory must be saved before the queue
+				// skips them on a restart
+				force = spilled == 0 && r.q.len() > 0
+				r.cp.Seq = seqEnd
+				if height := msg.Receipts[len(msg.Receipts)-1].Height; height > msg.Height {
+					msg.Height = height
+				}
+			}
+			if msg.Height > r.cp.Height {
+				r.cp.Height = msg.Height
+				relayMetrics.srcHeight.set(r.cfg.Name, float64(r.cp.Height))
+			}
+			r.saveCheckpoint(srcMsg.Receipts, force)
+			relayMetrics.pendingReceipts.set(r.cfg.Name, float64(backlog()))
+			r.state.setBacklog(r.cp.Height, backlog(), false)
+			if len(msg.Receipts) > 0 && backlog() > r.loop.triggerReceiptsCount {
+				relaySignal()
 			}
 
 		case <-relayCh:
 
+			if r.ctl.state() == RelayStateDraining {
+				continue // no new relay tx, drainCh ends the loop
+			}
+			if r.ctl.isPaused() || r.rw.isBelowFloor() {
+				continue // keep scanning the source, but don't send
+			}
+
 			link, err = r.dst.Status(ctx)
 			if err != nil {
 				r.log.WithFields(log.Fields{"error": err}).Debug("dst.Status: failed")
@@ -180,79 +475,61 @@ func (r *relay) Start(ctx context.Context) error {
 				continue
 			}
 
+			relayMetrics.dstRxSeq.set(r.cfg.Name, float64(link.RxSeq))
+			relayMetrics.dstRxHeight.set(r.cfg.Name, float64(link.RxHeight))
+			if r.state.setLink(link) {
+				r.event(EventLink, &Event{Link: link})
+			}
+
 			if link.CurrentHeight < txBlockHeight {
 				continue // skip until dst.Status is updated
 			}
 
-			if missing := filterSrcMsg(link.RxHeight, link.RxSeq); missing > 0 {
-				r.log.WithFields(log.Fields{"rxSeq": missing}).Error("missing event sequence")
-				return fmt.Errorf("missing event sequence")
+			spilled := r.q.len()
+			if srcMsg.Receipts, err = r.q.refill(srcMsg.Receipts, link.RxSeq); err != nil {
+				return err
 			}
-
-			tx, newMsg, err := r.dst.Segment(ctx, srcMsg)
-			if err != nil {
+			if r.q.len() != spilled {
+				r.saveCheckpoint(srcMsg.Receipts, true)
+			}
+			if err := resync(link); err != nil {
 				return err
-			} else if tx == nil { // ignore if tx is nil
-				continue
 			}
+			relayMetrics.pendingReceipts.set(r.cfg.Name, float64(backlog()))
+			r.state.setBacklog(0, backlog(), false)
 
-		sendLoop:
-			for i, err := 1, tx.Send(ctx); true; i, err = i+1, tx.Send(ctx) {
-				switch {
-				case err == nil:
-					break sendLoop
-				case errors.Is(err, context.Canceled):
-					r.log.WithFields(log.Fields{"id": tx.ID(), "error": err}).Error("tx.Send failed")
+			if r.ctl.isShadow() {
+				if err := r.shadowRelay(ctx, srcMsg, link); err != nil {
 					return err
-				case errors.Is(err, chain.ErrInsufficientBalance):
-					r.log.WithFields(log.Fields{"error": err}).Errorf(
-						"add balance to relay account: waiting for %v", relayInsufficientBalanceWaitInterval)
-					time.Sleep(relayInsufficientBalanceWaitInterval)
-				default:
-					time.Sleep(relayTxSendWaitInterval) // wait before sending tx
-					if i > retryWarnThreshold {
-						r.log.WithFields(log.Fields{"error": err}).Warnf("tx.Send: retry=%d", i)
-					} else {
-						r.log.WithFields(log.Fields{"error": err}).Debugf("tx.Send: retry=%d", i)
-					}
 				}
+				continue
 			}
+			r.shadow.batches = nil
 
-			retryCount := 0
-		waitLoop:
-			for blockHeight, err := tx.Receipt(ctx); retryCount < 30; _, err = tx.Receipt(ctx) {
-				switch {
-				case err == nil:
-					newMsg.From = srcMsg.From
-					srcMsg = newMsg
-					txBlockHeight = blockHeight
-					break waitLoop
-				case errors.Is(err, context.Canceled):
-					r.log.WithFields(log.Fields{"error": err}).Error("tx.Receipt failed")
-					return err
-				case errors.Is(err, chain.ErrGasLimitExceeded):
-					// increase transaction gas limit
-				case errors.Is(err, chain.ErrBlockGasLimitExceeded):
-					// reduce batch size
-				case errors.Is(err, chain.ErrBMCRevertInvalidSeqNumber):
-					// messages skipped; refetch from source
-
-				default:
-					time.Sleep(relayTxReceiptWaitInterval) // wait before asking for receipt
-					if retryCount > retryWarnThreshold {
-						r.log.WithFields(log.Fields{"error": err, "retry": retryCount + 1}).Warn("tx.Receipt: ")
-					} else {
-						if strings.Contains(err.Error(), "not found") {
-							r.log.WithFields(log.Fields{"retry": retryCount + 1}).Debug("tx.Receipt: ")
-						} else {
-							r.log.WithFields(log.Fields{"error": err, "retry": retryCount + 1}).Debug("tx.Receipt: ")
-						}
+			if !r.canSubmit(link, len(srcMsg.Receipts) > 0) {
+				continue
+			}
 
-					}
+			var held *HeldMessage
+			var isNew bool
+			relayMsg, held, isNew = r.pol.apply(srcMsg)
+			if err := r.pol.save(); err != nil {
+				return err
+			}
+			if held != nil {
+				if isNew {
+					r.hold(held)
+				}
+				if held.Halted {
+					continue
 				}
-				retryCount++
 			}
-
+			retry, err := r.pipeline(ctx, link, relayMsg, delivered)
+			if err != nil {
+				return err
+			} else if retry {
+				relaySignal()
+			}
 		}
 
 	}
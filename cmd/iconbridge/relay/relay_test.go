@@ -0,0 +1,227 @@
+package relay
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/db"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptReceiver ...
+// runs the scripts in turn on each subscription, and records its options
+type scriptReceiver struct {
+	mtx     sync.Mutex
+	opts    []chain.SubscribeOptions
+	scripts []func(opts chain.SubscribeOptions, send func(*chain.Message) bool)
+}
+
+func (sr *scriptReceiver) Subscribe(ctx context.Context, msgCh chan<- *chain.Message, opts chain.SubscribeOptions) (<-chan error, error) {
+	sr.mtx.Lock()
+	i := len(sr.opts)
+	sr.opts = append(sr.opts, opts)
+	sr.mtx.Unlock()
+	errCh := make(chan error)
+	go func() {
+		defer close(errCh)
+		send := func(msg *chain.Message) bool {
+			select {
+			case msgCh <- msg:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if i < len(sr.scripts) {
+			sr.scripts[i](opts, send)
+		}
+		<-ctx.Done()
+	}()
+	return errCh, nil
+}
+
+func (sr *scriptReceiver) Close() error { return nil }
+
+func (sr *scriptReceiver) options() []chain.SubscribeOptions {
+	sr.mtx.Lock()
+	defer sr.mtx.Unlock()
+	return append([]chain.SubscribeOptions(nil), sr.opts...)
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestRelayCheckpointScannedHeight(t *testing.T) {
+	cps, err := NewCheckpointStore(db.NewMapDB())
+	require.NoError(t, err)
+	scanned := make(chan struct{})
+	src := &scriptReceiver{scripts: []func(chain.SubscribeOptions, func(*chain.Message) bool){
+		func(opts chain.SubscribeOptions, send func(*chain.Message) bool) {
+			send(&chain.Message{Receipts: []*chain.Receipt{{Height: 10, Events: []*chain.Event{{Sequence: 1}}}}})
+			// blocks without events are reported by their height only
+			for h := uint64(11); h <= 50; h++ {
+				send(&chain.Message{Height: h})
+			}
+			close(scanned)
+		},
+	}}
+	r, err := newRelay(&RelayConfig{Name: "i2b"}, src, &segmentSender{}, cps, log.New())
+	require.NoError(t, err)
+	r.ctl.pause(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- r.Start(ctx) }()
+	waitFor(t, scanned)
+	cancel()
+	<-errCh
+
+	cp, err := cps.Load("i2b")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(50), cp.Height)
+	assert.Equal(t, uint64(1), cp.Seq)
+	require.Len(t, cp.Receipts, 1)
+
+	// a restart resumes after the height scanned
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() { errCh <- r.Start(ctx) }()
+	require.Eventually(t, func() bool { return len(src.options()) == 2 }, 10*time.Second, 10*time.Millisecond)
+	cancel()
+	<-errCh
+	assert.Equal(t, chain.SubscribeOptions{Seq: 1, Height: 51}, src.options()[1])
+}
+
+type linkTx struct {
+	s       *linkSender
+	receipt *chain.Receipt
+}
+
+func (tx *linkTx) ID() interface{}                   { return tx.receipt.Events[0].Sequence }
+func (tx *linkTx) Send(context.Context) error        { return nil }
+func (tx *linkTx) IncreaseGasLimit() (uint64, error) { return 0, chain.ErrGasLimitCeilingReached }
+func (tx *linkTx) Size() int                         { return 0 }
+func (tx *linkTx) Receipt(context.Context) (uint64, error) {
+	s := tx.s
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, e := range tx.receipt.Events {
+		if e.Sequence != s.link.RxSeq+1 {
+			s.rejected++
+			return 0, chain.ErrBMCRevertInvalidSeqNumber
+		}
+		s.link.RxSeq = e.Sequence
+		s.delivered = append(s.delivered, e.Sequence)
+		s.deliverCh <- e.Sequence
+	}
+	s.link.RxHeight = tx.receipt.Height
+	s.link.CurrentHeight++
+	return s.link.CurrentHeight, nil
+}
+
+// linkSender ...
+// delivers a receipt in each relay tx like the destination bmc, which
+// rejects the sequences out of order, and reports them in its link status
+type linkSender struct {
+	segmentSender
+	mtx       sync.Mutex
+	link      chain.BMCLinkStatus
+	delivered []uint64
+	rejected  int
+	deliverCh chan uint64
+}
+
+func (s *linkSender) Status(context.Context) (*chain.BMCLinkStatus, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	link := s.link
+	return &link, nil
+}
+
+func (s *linkSender) Segment(ctx context.Context, msg *chain.Message, opts chain.SegmentOptions) (chain.RelayTx, *chain.Message, error) {
+	if len(msg.Receipts) == 0 {
+		return nil, msg, nil
+	}
+	return &linkTx{s: s, receipt: msg.Receipts[0]}, &chain.Message{From: msg.From, Receipts: msg.Receipts[1:]}, nil
+}
+
+func TestRelayResyncSequenceGap(t *testing.T) {
+	cps, err := NewCheckpointStore(db.NewMapDB())
+	require.NoError(t, err)
+	event := func(seq uint64) *chain.Message {
+		return &chain.Message{Receipts: []*chain.Receipt{{Height: 10 + seq, Events: []*chain.Event{{Sequence: seq}}}}}
+	}
+	dst := &linkSender{link: chain.BMCLinkStatus{RxHeight: 5}, deliverCh: make(chan uint64, 8)}
+	delivered := func(seq uint64) {
+		for {
+			select {
+			case s := <-dst.deliverCh:
+				if s == seq {
+					return
+				}
+			case <-time.After(10 * time.Second):
+				t.Errorf("seq %d not delivered", seq)
+				return
+			}
+		}
+	}
+	resubscribed := make(chan *Checkpoint, 1)
+	src := &scriptReceiver{scripts: []func(chain.SubscribeOptions, func(*chain.Message) bool){
+		func(opts chain.SubscribeOptions, send func(*chain.Message) bool) {
+			send(event(1))
+			send(event(2))
+			delivered(2)
+			send(event(4)) // seq 3 is missing
+		},
+		func(opts chain.SubscribeOptions, send func(*chain.Message) bool) {
+			cp, _ := cps.Load("i2b")
+			resubscribed <- cp
+			for seq := opts.Seq + 1; seq <= 4; seq++ {
+				send(event(seq))
+			}
+		},
+	}}
+	r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{TickerInterval: 0.1}}, src, dst, cps, log.New())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- r.Start(ctx) }()
+
+	// the relay resubscribes after the last sequence delivered, from its
+	// height, and drops the checkpoint beyond it
+	var cp *Checkpoint
+	select {
+	case cp = <-resubscribed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("not resubscribed")
+	}
+	delivered(4)
+	cancel()
+	<-errCh
+
+	opts := src.options()
+	require.Len(t, opts, 2)
+	assert.Equal(t, chain.SubscribeOptions{Seq: 0, Height: 5}, opts[0])
+	assert.Equal(t, chain.SubscribeOptions{Seq: 2, Height: 12}, opts[1])
+	require.NotNil(t, cp)
+	assert.Equal(t, uint64(2), cp.Seq)
+	assert.Equal(t, uint64(2), cp.RxSeq)
+	assert.Equal(t, uint64(11), cp.Height)
+	assert.Empty(t, cp.Receipts)
+
+	dst.mtx.Lock()
+	defer dst.mtx.Unlock()
+	assert.Equal(t, []uint64{1, 2, 3, 4}, dst.delivered)
+	assert.Zero(t, dst.rejected)
+}
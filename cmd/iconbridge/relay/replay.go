@@ -0,0 +1,195 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+const (
+	defaultReplayTimeout = 5 * time.Minute
+)
+
+// ReplayOptions ...
+// selects the source messages to replay
+type ReplayOptions struct {
+	FromHeight uint64
+	ToHeight   uint64
+	FromSeq    uint64        // the one following the last delivered sequence if zero
+	ToSeq      uint64        // unbounded if zero
+	Timeout    time.Duration // to scan the source up to ToHeight
+}
+
+// ReplayTx ...
+// is a relay tx carrying the receipts from SeqBegin to SeqEnd
+type ReplayTx struct {
+	chain.RelayTx
+	SeqBegin uint64
+	SeqEnd   uint64
+	Receipts int
+}
+
+// Replay ...
+// rebuilds the messages of a relay from its source, so that stuck or
+// skipped messages can be relayed manually without restarting the relay
+// from a different offset
+type Replay struct {
+	r *relay
+}
+
+func NewReplay(cfg *RelayConfig, l log.Logger) (*Replay, error) {
+	src, dst, l, err := newChains(cfg, l)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRelay(cfg, src, dst, nil, l.WithFields(log.Fields{log.FieldKeyChain: "replay"}))
+	if err != nil {
+		src.Close()
+		dst.Close()
+		return nil, fmt.Errorf("relay %v err %v", cfg.Name, err)
+	}
+	return &Replay{r: r}, nil
+}
+
+// Close ...
+// releases the receiver and the sender of the relay
+func (rp *Replay) Close() {
+	rp.r.close()
+}
+
+// Status ...
+// returns the link status of the destination
+func (rp *Replay) Status(ctx context.Context) (*chain.BMCLinkStatus, error) {
+	return rp.r.dst.Status(ctx)
+}
+
+// Collect ...
+// scans the source from opts.FromHeight to opts.ToHeight, and returns the
+// receipts with events from opts.FromSeq to opts.ToSeq; it ends once the
+// receiver reports the scan of opts.ToHeight, or when opts.Timeout expires
+func (rp *Replay) Collect(ctx context.Context, opts ReplayOptions) (*chain.Message, error) {
+	if opts.ToHeight < opts.FromHeight || (opts.ToSeq != 0 && opts.ToSeq < opts.FromSeq) {
+		return nil, fmt.Errorf("invalid range: height=[%d %d] seq=[%d %d]",
+			opts.FromHeight, opts.ToHeight, opts.FromSeq, opts.ToSeq)
+	}
+	if opts.FromSeq == 0 {
+		link, err := rp.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.FromSeq = link.RxSeq + 1
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultReplayTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	msgCh := make(chan *chain.Message, 16)
+	errCh, err := rp.r.src.Subscribe(ctx, msgCh, chain.SubscribeOptions{
+		Seq:    opts.FromSeq - 1,
+		Height: opts.FromHeight,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &chain.Message{From: rp.r.cfg.Src.Address}
+	for {
+		select {
+		case <-ctx.Done():
+			if len(msg.Receipts) == 0 {
+				return nil, fmt.Errorf("no message found: %v", ctx.Err())
+			}
+			rp.r.log.WithFields(log.Fields{"timeout": opts.Timeout}).Warn("replay: source scan timed out")
+			return msg, nil
+		case err := <-errCh:
+			if err == nil {
+				return msg, nil
+			}
+			return nil, err
+		case m := <-msgCh:
+			for _, receipt := range m.Receipts {
+				if receipt.Height > opts.ToHeight {
+					return msg, nil
+				}
+				events := receipt.Events[:0]
+				for _, event := range receipt.Events {
+					if opts.ToSeq == 0 || event.Sequence <= opts.ToSeq {
+						events = append(events, event)
+					}
+				}
+				receipt.Events = events
+				if len(receipt.Events) > 0 {
+					msg.Receipts = append(msg.Receipts, receipt)
+				}
+				if opts.ToSeq != 0 && lastSequence(receipt) >= opts.ToSeq {
+					return msg, nil
+				}
+			}
+			if m.Height >= opts.ToHeight {
+				return msg, nil // scanned up to ToHeight
+			}
+		}
+	}
+}
+
+// Check ...
+// runs "msg" through the policy of the relay, as the relay does before
+// segmenting messages, and returns the messages preceding the first one
+// it holds, and that one, if any; the policy state in the relay db isn't
+// read, so messages approved on the relay are held too, and the circuit
+// breakers count the transfers of "msg" only
+func (rp *Replay) Check(msg *chain.Message) (allowed *chain.Message, held *HeldMessage) {
+	allowed, held, _ = rp.r.pol.apply(msg)
+	return allowed, held
+}
+
+// Segment ...
+// returns the relay txs carrying "msg"
+func (rp *Replay) Segment(ctx context.Context, msg *chain.Message) ([]*ReplayTx, error) {
+	var txs []*ReplayTx
+	for len(msg.Receipts) > 0 {
+		tx, newMsg, err := rp.r.dst.Segment(ctx, msg, chain.SegmentOptions{})
+		if err != nil {
+			return nil, err
+		}
+		n := len(msg.Receipts) - len(newMsg.Receipts)
+		if tx == nil || n == 0 {
+			return nil, fmt.Errorf("failed to segment receipts from height %d", msg.Receipts[0].Height)
+		}
+		rtx := &ReplayTx{RelayTx: tx, Receipts: n}
+		rtx.SeqBegin, rtx.SeqEnd = sequenceRange(msg.Receipts[:n])
+		txs = append(txs, rtx)
+		msg = newMsg
+	}
+	return txs, nil
+}
+
+// Send ...
+// sends "tx" and returns the destination block height including it
+func (rp *Replay) Send(ctx context.Context, tx *ReplayTx) (blockHeight uint64, err error) {
+	itx := &inflightTx{
+		tx:       tx.RelayTx,
+		receipts: tx.Receipts,
+		seqBegin: tx.SeqBegin,
+		seqEnd:   tx.SeqEnd,
+	}
+	for {
+		if err := rp.r.send(ctx, itx); err != nil {
+			return 0, err
+		}
+		blockHeight, outcome, err := rp.r.confirm(ctx, itx)
+		switch {
+		case err != nil:
+			return 0, err
+		case outcome == txDelivered:
+			return blockHeight, nil
+		case outcome != txResend:
+			return 0, fmt.Errorf("relay tx %v not delivered", tx.ID())
+		}
+	}
+}
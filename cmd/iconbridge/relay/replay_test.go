@@ -0,0 +1,108 @@
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayReceiver ...
+// sends a receipt per height with the event sequence of the height
+type replayReceiver struct{ opts chain.SubscribeOptions }
+
+func (rr *replayReceiver) Subscribe(ctx context.Context, msgCh chan<- *chain.Message, opts chain.SubscribeOptions) (<-chan error, error) {
+	rr.opts = opts
+	go func() {
+		for h := opts.Height; ; h++ {
+			msg := &chain.Message{Receipts: []*chain.Receipt{{Height: h, Events: []*chain.Event{{Sequence: h}}}}}
+			select {
+			case msgCh <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return make(chan error), nil
+}
+
+func (rr *replayReceiver) Close() error { return nil }
+
+func TestReplay(t *testing.T) {
+	src, dst := &replayReceiver{}, &pipelinedSender{}
+	r, err := newRelay(&RelayConfig{Name: "i2b"}, src, dst, nil, log.New())
+	require.NoError(t, err)
+	rp := &Replay{r: r}
+
+	msg, err := rp.Collect(context.Background(), ReplayOptions{FromHeight: 10, ToHeight: 20, FromSeq: 12, ToSeq: 13})
+	require.NoError(t, err)
+	assert.Equal(t, chain.SubscribeOptions{Seq: 11, Height: 10}, src.opts)
+	// the fake receiver doesn't filter sequences, the range is applied to the heights
+	require.Len(t, msg.Receipts, 4)
+	assert.Equal(t, uint64(13), lastSequence(msg.Receipts[3]))
+
+	msg, err = rp.Collect(context.Background(), ReplayOptions{FromHeight: 10, ToHeight: 12})
+	require.NoError(t, err)
+	assert.Equal(t, chain.SubscribeOptions{Seq: 0, Height: 10}, src.opts)
+	require.Len(t, msg.Receipts, 3)
+
+	txs, err := rp.Segment(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, txs, 3)
+	assert.Equal(t, []uint64{12, 12}, []uint64{txs[2].SeqBegin, txs[2].SeqEnd})
+
+	height, err := rp.Send(context.Background(), txs[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), height)
+
+	_, err = rp.Collect(context.Background(), ReplayOptions{FromHeight: 10, ToHeight: 9})
+	assert.Error(t, err)
+}
+
+func TestReplayScannedHeight(t *testing.T) {
+	// blocks without events are reported by their height only
+	src := &scriptReceiver{scripts: []func(chain.SubscribeOptions, func(*chain.Message) bool){
+		func(opts chain.SubscribeOptions, send func(*chain.Message) bool) {
+			send(&chain.Message{Receipts: []*chain.Receipt{{Height: 11, Events: []*chain.Event{{Sequence: 5}}}}})
+			for h := uint64(12); h <= 30; h++ {
+				send(&chain.Message{Height: h})
+			}
+		},
+	}}
+	r, err := newRelay(&RelayConfig{Name: "i2b"}, src, &pipelinedSender{}, nil, log.New())
+	require.NoError(t, err)
+	rp := &Replay{r: r}
+
+	// the scan ends at ToHeight, before the timeout
+	start := time.Now()
+	msg, err := rp.Collect(context.Background(), ReplayOptions{FromHeight: 10, ToHeight: 20, FromSeq: 5, Timeout: time.Minute})
+	require.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+	require.Len(t, msg.Receipts, 1)
+	assert.Equal(t, uint64(5), lastSequence(msg.Receipts[0]))
+}
+
+func TestReplayCheck(t *testing.T) {
+	r, err := newRelay(&RelayConfig{Name: "i2b", Policy: &PolicyConfig{HoldAbove: map[string]string{"ICX": "100"}}},
+		&replayReceiver{}, &pipelinedSender{}, nil, log.New())
+	require.NoError(t, err)
+	rp := &Replay{r: r}
+
+	msg := &chain.Message{Receipts: []*chain.Receipt{
+		{Height: 10, Events: []*chain.Event{btpEvent(t, 1, "bts", "ICX", 10)}},
+		{Height: 11, Events: []*chain.Event{btpEvent(t, 2, "bts", "ICX", 1000)}},
+	}}
+	allowed, held := rp.Check(msg)
+	require.NotNil(t, held)
+	assert.Equal(t, uint64(2), held.Seq)
+	require.Len(t, allowed.Receipts, 1)
+	assert.Equal(t, uint64(1), lastSequence(allowed.Receipts[0]))
+
+	allowed, held = rp.Check(&chain.Message{Receipts: msg.Receipts[:1]})
+	assert.Nil(t, held)
+	assert.Len(t, allowed.Receipts, 1)
+}
@@ -0,0 +1,123 @@
+package relay
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRestartMinInterval   = 5 * time.Second
+	defaultRestartMaxInterval   = 5 * time.Minute
+	defaultRestartMultiplier    = 2
+	defaultRestartJitter        = 0.2
+	defaultRestartMaxFailures   = 10
+	defaultRestartFailureWindow = 30 * time.Minute
+	defaultRestartMinUptime     = 5 * time.Minute
+)
+
+// RestartConfig ...
+// is the policy restarting a failed relay; zero values take the defaults
+type RestartConfig struct {
+	MinInterval   uint    `json:"min_interval"`   // seconds before the first restart
+	MaxInterval   uint    `json:"max_interval"`   // seconds, upper bound of the backoff
+	Multiplier    float64 `json:"multiplier"`     // backoff growth after each consecutive failure
+	Jitter        float64 `json:"jitter"`         // random fraction added to or removed from the backoff
+	MaxFailures   uint    `json:"max_failures"`   // failures within failure_window before quarantine
+	FailureWindow uint    `json:"failure_window"` // seconds
+	Quarantine    uint    `json:"quarantine"`     // seconds in quarantine, failure_window by default
+	MinUptime     uint    `json:"min_uptime"`     // seconds a relay must run for its failures to be forgotten
+}
+
+// restartPolicy ...
+// backs off exponentially on consecutive failures and quarantines a relay
+// failing too often, so that it doesn't flood logs and RPC providers;
+// a relay running healthy for minUptime starts over
+type restartPolicy struct {
+	minInterval   time.Duration
+	maxInterval   time.Duration
+	multiplier    float64
+	jitter        float64
+	maxFailures   int
+	failureWindow time.Duration
+	quarantine    time.Duration
+	minUptime     time.Duration
+
+	failures []time.Time // consecutive, within failureWindow
+}
+
+func newRestartPolicy(cfg *RestartConfig) restartPolicy {
+	p := restartPolicy{
+		minInterval:   defaultRestartMinInterval,
+		maxInterval:   defaultRestartMaxInterval,
+		multiplier:    defaultRestartMultiplier,
+		jitter:        defaultRestartJitter,
+		maxFailures:   defaultRestartMaxFailures,
+		failureWindow: defaultRestartFailureWindow,
+		quarantine:    defaultRestartFailureWindow,
+		minUptime:     defaultRestartMinUptime,
+	}
+	if cfg == nil {
+		return p
+	}
+	if cfg.MinInterval > 0 {
+		p.minInterval = time.Duration(cfg.MinInterval) * time.Second
+	}
+	if cfg.MaxInterval > 0 {
+		p.maxInterval = time.Duration(cfg.MaxInterval) * time.Second
+	}
+	if cfg.Multiplier >= 1 {
+		p.multiplier = cfg.Multiplier
+	}
+	if cfg.Jitter > 0 && cfg.Jitter < 1 {
+		p.jitter = cfg.Jitter
+	}
+	if cfg.MaxFailures > 0 {
+		p.maxFailures = int(cfg.MaxFailures)
+	}
+	if cfg.FailureWindow > 0 {
+		p.failureWindow = time.Duration(cfg.FailureWindow) * time.Second
+		p.quarantine = p.failureWindow
+	}
+	if cfg.Quarantine > 0 {
+		p.quarantine = time.Duration(cfg.Quarantine) * time.Second
+	}
+	if cfg.MinUptime > 0 {
+		p.minUptime = time.Duration(cfg.MinUptime) * time.Second
+	}
+	return p
+}
+
+// failed ...
+// records the failure at "now" of a run since "started", and returns the
+// delay before restarting the relay, and whether it's quarantined meanwhile
+func (p *restartPolicy) failed(started, now time.Time) (delay time.Duration, quarantine bool) {
+	if now.Sub(started) >= p.minUptime {
+		p.failures = nil
+	}
+	failures := p.failures[:0]
+	for _, t := range p.failures {
+		if now.Sub(t) < p.failureWindow {
+			failures = append(failures, t)
+		}
+	}
+	p.failures = append(failures, now)
+	if len(p.failures) >= p.maxFailures {
+		return p.quarantine, true
+	}
+
+	backoff := float64(p.minInterval) * math.Pow(p.multiplier, float64(len(p.failures)-1))
+	if backoff > float64(p.maxInterval) {
+		backoff = float64(p.maxInterval)
+	}
+	backoff += backoff * p.jitter * (2*rand.Float64() - 1)
+	return time.Duration(backoff), false
+}
+
+func (p *restartPolicy) consecutiveFailures() int {
+	return len(p.failures)
+}
+
+func (p *restartPolicy) reset() {
+	p.failures = nil
+}
@@ -0,0 +1,50 @@
+package relay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartPolicy(t *testing.T) {
+	p := newRestartPolicy(&RestartConfig{
+		MinInterval:   1,
+		MaxInterval:   5,
+		Jitter:        0.1,
+		MaxFailures:   5,
+		FailureWindow: 60,
+	})
+	now := time.Now()
+	for i, want := range []time.Duration{1, 2, 4, 5} {
+		at := now.Add(time.Duration(i) * time.Second)
+		delay, quarantine := p.failed(at, at)
+		assert.False(t, quarantine)
+		assert.InDelta(t, float64(want*time.Second), float64(delay), float64(want*time.Second)/10)
+	}
+	// quarantined for the failure window by default
+	at := now.Add(4 * time.Second)
+	delay, quarantine := p.failed(at, at)
+	assert.True(t, quarantine)
+	assert.Equal(t, time.Minute, delay)
+
+	// failures out of the window don't count
+	p.reset()
+	p.failed(now, now)
+	at = now.Add(2 * time.Minute)
+	delay, quarantine = p.failed(at, at)
+	assert.False(t, quarantine)
+	assert.Equal(t, 1, p.consecutiveFailures())
+	assert.InDelta(t, float64(time.Second), float64(delay), float64(time.Second)/10)
+
+	// failures before a healthy run are forgotten
+	p.failed(at, at)
+	assert.Equal(t, 2, p.consecutiveFailures())
+	p.failed(at, at.Add(defaultRestartMinUptime))
+	assert.Equal(t, 1, p.consecutiveFailures())
+}
+
+func TestRestartPolicyQuarantine(t *testing.T) {
+	assert.Equal(t, defaultRestartFailureWindow, newRestartPolicy(nil).quarantine)
+	assert.Equal(t, 10*time.Second, newRestartPolicy(&RestartConfig{FailureWindow: 60, Quarantine: 10}).quarantine)
+}
@@ -0,0 +1,58 @@
+package relay
+
+import (
+	"fmt"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+)
+
+// RotationConfig ...
+// position of this relayer in the destination BMC's relayer rotation
+type RotationConfig struct {
+	Index uint `json:"bmr_index"` // index of this relayer in the BMC's relayer list
+	Count uint `json:"bmr_count"` // number of relayers registered for the link
+}
+
+func (cfg *RotationConfig) validate() error {
+	if cfg.Index >= cfg.Count {
+		return fmt.Errorf("bmr_index %d must be less than bmr_count %d", cfg.Index, cfg.Count)
+	}
+	return nil
+}
+
+// rotation ...
+// follows the BMC's relayer rotation schedule, so that registered
+// relayers take turns instead of racing each other
+type rotation struct {
+	cfg *RotationConfig
+
+	rxSeq         uint64 // last delivered sequence seen on the link
+	pendingHeight uint64 // dst height since which messages have been pending without delivery
+}
+
+// activeIndex ...
+// returns the index of the relayer whose turn it is at the current dst height
+func (rt *rotation) activeIndex(link *chain.BMCLinkStatus) uint {
+	idx := uint64(link.BMRIndex)
+	if link.CurrentHeight >= link.RotateHeight {
+		idx += (link.CurrentHeight-link.RotateHeight)/uint64(link.RotateTerm) + 1
+	}
+	return uint(idx % uint64(rt.cfg.Count))
+}
+
+// check ...
+// returns whether it's this relayer's turn, and whether the active relayer
+// has left pending messages undelivered for more than DelayLimit dst blocks
+func (rt *rotation) check(link *chain.BMCLinkStatus, pending bool) (turn, delayed bool) {
+	switch {
+	case !pending:
+		rt.pendingHeight = 0
+	case link.RxSeq != rt.rxSeq || rt.pendingHeight == 0:
+		rt.pendingHeight = link.CurrentHeight
+	}
+	rt.rxSeq = link.RxSeq
+	turn = rt.activeIndex(link) == rt.cfg.Index
+	delayed = pending && link.DelayLimit > 0 &&
+		link.CurrentHeight > rt.pendingHeight+uint64(link.DelayLimit)
+	return turn, delayed
+}
@@ -0,0 +1,45 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotation(t *testing.T) {
+	rt := &rotation{cfg: &RotationConfig{Index: 1, Count: 3}}
+	link := &chain.BMCLinkStatus{
+		RxSeq:         10,
+		BMRIndex:      0,
+		RotateHeight:  100,
+		RotateTerm:    10,
+		DelayLimit:    5,
+		CurrentHeight: 95,
+	}
+
+	assert.Equal(t, uint(0), rt.activeIndex(link))
+	turn, delayed := rt.check(link, true)
+	assert.False(t, turn)
+	assert.False(t, delayed)
+
+	// active relayer didn't deliver for more than DelayLimit blocks
+	link.CurrentHeight = 99
+	turn, delayed = rt.check(link, true)
+	assert.False(t, turn)
+	assert.False(t, delayed)
+	link.CurrentHeight = 101
+	turn, delayed = rt.check(link, true)
+	assert.True(t, turn)
+	assert.True(t, delayed)
+
+	// delivery resets the delay
+	link.RxSeq, link.CurrentHeight = 12, 115
+	turn, delayed = rt.check(link, true)
+	assert.False(t, turn)
+	assert.False(t, delayed)
+	assert.Equal(t, uint(2), rt.activeIndex(link))
+
+	link.CurrentHeight = 130
+	assert.Equal(t, uint(1), rt.activeIndex(link))
+}
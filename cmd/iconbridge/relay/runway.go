@@ -0,0 +1,173 @@
+package relay
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRunwayWindow = 24 * time.Hour
+)
+
+var (
+	defaultRunwayWarn = []uint{72, 24}
+)
+
+// RunwayConfig ...
+// forecasts how long the relay wallet lasts at the current spend rate
+type RunwayConfig struct {
+	Warn   []uint `json:"warn"`   // hours of runway to warn at, 72 and 24 if empty
+	Window uint   `json:"window"` // seconds of spend history the forecast is based on, a day if zero
+	Floor  string `json:"floor"`  // balance below which the relay stops sending, in the smallest unit of the coin; none if empty
+}
+
+// RunwayStatus ...
+// is the relay wallet forecast reported by the status API
+type RunwayStatus struct {
+	Balance    *big.Int `json:"balance"`
+	SpendRate  float64  `json:"spend_rate"`       // smallest units of the coin spent per hour
+	Runway     float64  `json:"runway,omitempty"` // hours until the wallet is empty, zero if nothing is spent
+	BelowFloor bool     `json:"below_floor"`
+}
+
+// runway ...
+// tracks the spend rate of the relay wallet from its balance changes and
+// the fees of the relay txs, warns as the forecast time-to-empty crosses
+// the thresholds, and stops the relay from sending below the floor
+type runway struct {
+	warn   []time.Duration // descending
+	window time.Duration
+	floor  *big.Int
+
+	mtx        sync.Mutex
+	balances   []balanceSample // within window
+	fees       []balanceSample // within window
+	balance    *big.Int
+	rate       float64 // per hour
+	belowFloor bool
+	warned     int // index in warn of the lowest threshold crossed, -1 if none
+}
+
+type balanceSample struct {
+	at    time.Time
+	value *big.Int
+}
+
+func newRunway(cfg *RunwayConfig) (*runway, error) {
+	rw := &runway{window: defaultRunwayWindow, warned: -1}
+	hours := defaultRunwayWarn
+	if cfg != nil {
+		if len(cfg.Warn) > 0 {
+			hours = cfg.Warn
+		}
+		if cfg.Window > 0 {
+			rw.window = time.Duration(cfg.Window) * time.Second
+		}
+		if cfg.Floor != "" {
+			floor, ok := new(big.Int).SetString(cfg.Floor, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid floor %q", cfg.Floor)
+			}
+			rw.floor = floor
+		}
+	}
+	for _, h := range hours {
+		rw.warn = append(rw.warn, time.Duration(h)*time.Hour)
+	}
+	sort.Slice(rw.warn, func(i, j int) bool { return rw.warn[i] > rw.warn[j] })
+	return rw, nil
+}
+
+// spent ...
+// records the fee of a relay tx
+func (rw *runway) spent(fee *big.Int) {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+	rw.fees = append(rw.fees, balanceSample{at: time.Now(), value: fee})
+}
+
+// update ...
+// records the balance "bal" and returns the forecast time-to-empty, zero if
+// nothing is spent, and the threshold it newly crossed, zero if none
+func (rw *runway) update(bal *big.Int, now time.Time) (left, crossed time.Duration) {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+	rw.balance = bal
+	rw.balances = append(rw.balances, balanceSample{at: now, value: bal})
+	rw.balances = samplesSince(rw.balances, now.Add(-rw.window))
+	rw.fees = samplesSince(rw.fees, now.Add(-rw.window))
+	rw.belowFloor = rw.floor != nil && bal.Cmp(rw.floor) < 0
+
+	// spent: the balance drops, ignoring top-ups, or the fees if larger
+	// as the balance is sampled less often than txs are confirmed
+	drops, fees := new(big.Int), new(big.Int)
+	for i := 1; i < len(rw.balances); i++ {
+		if d := new(big.Int).Sub(rw.balances[i-1].value, rw.balances[i].value); d.Sign() > 0 {
+			drops.Add(drops, d)
+		}
+	}
+	for _, f := range rw.fees {
+		fees.Add(fees, f.value)
+	}
+	spent := drops
+	if fees.Cmp(drops) > 0 {
+		spent = fees
+	}
+	since := rw.balances[0].at
+	if len(rw.fees) > 0 && rw.fees[0].at.Before(since) {
+		since = rw.fees[0].at
+	}
+	rw.rate = 0
+	if elapsed := now.Sub(since); elapsed > 0 && spent.Sign() > 0 {
+		rw.rate = bigToFloat(spent) / elapsed.Hours()
+		left = time.Duration(bigToFloat(bal) / rw.rate * float64(time.Hour))
+	}
+
+	warned := -1
+	for i, w := range rw.warn {
+		if left > 0 && left <= w {
+			warned = i
+		}
+	}
+	if warned > rw.warned {
+		crossed = rw.warn[warned]
+	}
+	rw.warned = warned
+	return left, crossed
+}
+
+func (rw *runway) isBelowFloor() bool {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+	return rw.belowFloor
+}
+
+func (rw *runway) status() *RunwayStatus {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+	if rw.balance == nil {
+		return nil
+	}
+	st := &RunwayStatus{
+		Balance:    rw.balance,
+		SpendRate:  rw.rate,
+		BelowFloor: rw.belowFloor,
+	}
+	if rw.rate > 0 {
+		st.Runway = bigToFloat(rw.balance) / rw.rate
+	}
+	return st
+}
+
+// samplesSince ...
+// drops the samples older than "t"
+func samplesSince(samples []balanceSample, t time.Time) []balanceSample {
+	i := 0
+	for i < len(samples) && samples[i].at.Before(t) {
+		i++
+	}
+	return samples[i:]
+}
@@ -0,0 +1,59 @@
+package relay
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunway(t *testing.T) {
+	rw, err := newRunway(&RunwayConfig{Warn: []uint{24, 72}, Floor: "100"})
+	require.NoError(t, err)
+	now := time.Now()
+
+	left, crossed := rw.update(big.NewInt(100000), now)
+	assert.Zero(t, left)
+	assert.Zero(t, crossed)
+	assert.Zero(t, rw.status().Runway)
+
+	// 1000 per hour: 99 hours left
+	left, crossed = rw.update(big.NewInt(99000), now.Add(time.Hour))
+	assert.Equal(t, 99*time.Hour, left)
+	assert.Zero(t, crossed)
+
+	// 5000 in 2 hours, 38 hours left
+	left, crossed = rw.update(big.NewInt(95000), now.Add(2*time.Hour))
+	assert.Equal(t, 38*time.Hour, left.Round(time.Hour))
+	assert.Equal(t, 72*time.Hour, crossed)
+	_, crossed = rw.update(big.NewInt(95000), now.Add(2*time.Hour))
+	assert.Zero(t, crossed, "warned once")
+
+	left, crossed = rw.update(big.NewInt(50), now.Add(3*time.Hour))
+	assert.Equal(t, 24*time.Hour, crossed)
+	assert.True(t, rw.isBelowFloor())
+	st := rw.status()
+	assert.True(t, st.BelowFloor)
+	assert.InDelta(t, left.Hours(), st.Runway, 1e-6)
+
+	// top-ups aren't spending
+	rate := st.SpendRate
+	rw.update(big.NewInt(1000000), now.Add(4*time.Hour))
+	assert.False(t, rw.isBelowFloor())
+	assert.InDelta(t, rate*3/4, rw.status().SpendRate, 1e-6)
+
+	_, err = newRunway(&RunwayConfig{Floor: "0x10"})
+	assert.Error(t, err)
+}
+
+func TestRunwayFees(t *testing.T) {
+	rw, err := newRunway(nil)
+	require.NoError(t, err)
+	now := time.Now()
+	rw.fees = []balanceSample{{at: now.Add(-2 * time.Hour), value: big.NewInt(200)}}
+	left, _ := rw.update(big.NewInt(1000), now)
+	assert.Equal(t, 10*time.Hour, left)
+	assert.False(t, rw.isBelowFloor())
+}
@@ -0,0 +1,90 @@
+package relay
+
+import (
+	"context"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+// shadowBatch ...
+// is a relay message that a shadow relay would have sent
+type shadowBatch struct {
+	seqBegin, seqEnd uint64
+	receipts         int
+	size             int
+	at               time.Time
+}
+
+// shadowState ...
+// tracks the relay messages a shadow relay would have sent, until the
+// destination shows them delivered by the active relayer
+type shadowState struct {
+	batches []*shadowBatch
+}
+
+// shadowRelay ...
+// segments the pending messages exactly as usual, but instead of sending
+// them, logs each new relay message and compares it with what the active
+// relayer delivered, as seen through the link status
+func (r *relay) shadowRelay(ctx context.Context, srcMsg *chain.Message, link *chain.BMCLinkStatus) error {
+	delivered := 0
+	for _, b := range r.shadow.batches {
+		if b.seqEnd > link.RxSeq {
+			break
+		}
+		r.log.WithFields(log.Fields{
+			"seq":   []uint64{b.seqBegin, b.seqEnd},
+			"delay": time.Since(b.at).Truncate(time.Millisecond),
+		}).Info("shadow: delivered by active relayer")
+		delivered++
+	}
+	r.shadow.batches = r.shadow.batches[delivered:]
+	if delivered > 0 {
+		r.state.setBacklog(0, len(srcMsg.Receipts), true)
+	}
+	if link.RxSeq > r.cp.Seq {
+		r.log.WithFields(log.Fields{
+			"rxSeq": link.RxSeq,
+			"seq":   r.cp.Seq,
+		}).Warn("shadow: behind active relayer")
+	}
+
+	lastSeq := link.RxSeq
+	if n := len(r.shadow.batches); n > 0 {
+		lastSeq = r.shadow.batches[n-1].seqEnd
+	}
+	msg := &chain.Message{From: srcMsg.From, Receipts: srcMsg.Receipts}
+	for len(msg.Receipts) > 0 {
+		tx, newMsg, err := r.dst.Segment(ctx, msg, r.segmentOptions(link))
+		if err != nil {
+			return err
+		} else if tx == nil {
+			break
+		}
+		packed := msg.Receipts[:len(msg.Receipts)-len(newMsg.Receipts)]
+		if len(packed) == 0 {
+			break
+		}
+		msg = newMsg
+		last := packed[len(packed)-1]
+		b := &shadowBatch{
+			seqBegin: packed[0].Events[0].Sequence,
+			seqEnd:   last.Events[len(last.Events)-1].Sequence,
+			receipts: len(packed),
+			size:     tx.Size(),
+			at:       time.Now(),
+		}
+		if b.seqEnd <= lastSeq {
+			continue // already reported
+		}
+		r.shadow.batches = append(r.shadow.batches, b)
+		r.log.WithFields(log.Fields{
+			"seq":      []uint64{b.seqBegin, b.seqEnd},
+			"receipts": b.receipts,
+			"size":     b.size,
+		}).Info("shadow: would send relay message")
+	}
+	return nil
+}
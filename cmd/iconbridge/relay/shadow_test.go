@@ -0,0 +1,73 @@
+package relay
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type segmentTx struct{ receipts int }
+
+func (tx *segmentTx) ID() interface{}                         { return nil }
+func (tx *segmentTx) Send(ctx context.Context) error          { panic("shadow relay must not send") }
+func (tx *segmentTx) Receipt(context.Context) (uint64, error) { return 0, nil }
+func (tx *segmentTx) IncreaseGasLimit() (uint64, error)       { return 0, chain.ErrGasLimitCeilingReached }
+func (tx *segmentTx) Size() int                               { return tx.receipts * 100 }
+
+// segmentSender ...
+// packs up to "max" receipts into each relay tx
+type segmentSender struct{ max int }
+
+func (s *segmentSender) Status(context.Context) (*chain.BMCLinkStatus, error) {
+	return &chain.BMCLinkStatus{}, nil
+}
+
+func (s *segmentSender) Balance(context.Context) (*big.Int, *big.Int, error) {
+	return big.NewInt(0), big.NewInt(0), nil
+}
+
+func (s *segmentSender) Close() error { return nil }
+
+func (s *segmentSender) Segment(ctx context.Context, msg *chain.Message, opts chain.SegmentOptions) (chain.RelayTx, *chain.Message, error) {
+	if len(msg.Receipts) == 0 {
+		return nil, msg, nil
+	}
+	n := s.max
+	if n > len(msg.Receipts) {
+		n = len(msg.Receipts)
+	}
+	return &segmentTx{receipts: n}, &chain.Message{From: msg.From, Receipts: msg.Receipts[n:]}, nil
+}
+
+func TestShadowRelay(t *testing.T) {
+	r, err := newRelay(&RelayConfig{Name: "i2b", Shadow: true}, nil, &segmentSender{max: 2}, nil, log.New())
+	require.NoError(t, err)
+	var receipts []*chain.Receipt
+	for seq := uint64(1); seq <= 5; seq++ {
+		receipts = append(receipts, &chain.Receipt{Height: seq, Events: []*chain.Event{{Sequence: seq}}})
+	}
+	r.cp.Seq = 5
+	srcMsg := &chain.Message{Receipts: receipts}
+
+	require.NoError(t, r.shadowRelay(context.Background(), srcMsg, &chain.BMCLinkStatus{}))
+	require.Len(t, r.shadow.batches, 3)
+	assert.Equal(t, []uint64{1, 2}, []uint64{r.shadow.batches[0].seqBegin, r.shadow.batches[0].seqEnd})
+	assert.Equal(t, 200, r.shadow.batches[0].size)
+	assert.Equal(t, []uint64{5, 5}, []uint64{r.shadow.batches[2].seqBegin, r.shadow.batches[2].seqEnd})
+
+	// unchanged batches aren't reported twice
+	require.NoError(t, r.shadowRelay(context.Background(), srcMsg, &chain.BMCLinkStatus{}))
+	assert.Len(t, r.shadow.batches, 3)
+
+	// the active relayer delivered up to seq 3
+	srcMsg.Receipts = receipts[3:]
+	require.NoError(t, r.shadowRelay(context.Background(), srcMsg, &chain.BMCLinkStatus{RxSeq: 3}))
+	require.Len(t, r.shadow.batches, 2)
+	assert.Equal(t, uint64(4), r.shadow.batches[0].seqEnd)
+	assert.Equal(t, uint64(5), r.shadow.batches[1].seqEnd)
+}
@@ -0,0 +1,40 @@
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/icon-project/icon-bridge/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiRelayShutdown(t *testing.T) {
+	s := &pipelinedSender{sentCh: make(chan struct{}, 1)}
+	r, err := newRelay(&RelayConfig{Name: "i2b", Loop: &LoopConfig{TickerInterval: 0.1}},
+		&replayReceiver{}, s, nil, log.New())
+	require.NoError(t, err)
+	mr := &multiRelay{log: log.New(), relays: []*relay{r}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- mr.Start(ctx) }()
+	waitFor(t, s.sentCh)
+
+	sctx, scancel := context.WithTimeout(ctx, 5*time.Second)
+	defer scancel()
+	require.NoError(t, mr.Shutdown(sctx))
+	waitFor(t, r.ctl.done())
+	assert.Equal(t, RelayStateStopped, r.ctl.state())
+	assert.NotEmpty(t, s.sent)
+	assert.Equal(t, s.sent, s.confirmed)
+
+	// drained relays aren't started again
+	require.NoError(t, mr.Resume("i2b"))
+	assert.Equal(t, RelayStateStopped, r.ctl.state())
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+}
@@ -0,0 +1,136 @@
+package relay
+
+import (
+	"sync"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+)
+
+// RelayStatus ...
+// is a snapshot of a relay's progress, as reported by the admin API
+type RelayStatus struct {
+	Name       string               `json:"name"`
+	Src        chain.BTPAddress     `json:"src"`
+	Dst        chain.BTPAddress     `json:"dst"`
+	State      string               `json:"state"`
+	Shadow     bool                 `json:"shadow"`
+	Standby    bool                 `json:"standby"`
+	Subscribed bool                 `json:"subscribed"`
+	Link       *chain.BMCLinkStatus `json:"link,omitempty"`
+	SrcHeight  uint64               `json:"src_height"`
+	Backlog    int                  `json:"backlog"` // receipts waiting to be relayed
+	Lag        float64              `json:"lag"`     // seconds the backlog has been waiting without any delivery
+	LastError  string               `json:"last_error,omitempty"`
+	Restarts   int                  `json:"restarts"`
+	Crashes    int                  `json:"crashes"`
+	Failures   int                  `json:"failures"` // consecutive failures within the failure window
+	Held       *HeldMessage         `json:"held,omitempty"`
+	Runway     *RunwayStatus        `json:"runway,omitempty"`
+}
+
+// relayState ...
+// is the mutable part of RelayStatus shared between the relay loop and
+// the admin API
+type relayState struct {
+	mtx          sync.RWMutex
+	subscribed   bool
+	link         *chain.BMCLinkStatus
+	srcHeight    uint64
+	backlog      int
+	pendingSince time.Time // zero when there is no backlog
+	lastErr      error
+	restarts     int
+	crashes      int
+	failures     int
+}
+
+func (s *relayState) setSubscribed(subscribed bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.subscribed = subscribed
+}
+
+func (s *relayState) isSubscribed() bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.subscribed
+}
+
+// setLink ...
+// returns whether the messages sent or received on the link changed
+func (s *relayState) setLink(link *chain.BMCLinkStatus) (changed bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	changed = s.link == nil || s.link.RxSeq != link.RxSeq || s.link.TxSeq != link.TxSeq ||
+		s.link.RxHeight != link.RxHeight
+	s.link = link
+	return changed
+}
+
+// setBacklog ...
+// updates the backlog, "delivered" resets the lag to now
+func (s *relayState) setBacklog(srcHeight uint64, backlog int, delivered bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if srcHeight > 0 {
+		s.srcHeight = srcHeight
+	}
+	switch {
+	case backlog == 0:
+		s.pendingSince = time.Time{}
+	case delivered || s.pendingSince.IsZero():
+		s.pendingSince = time.Now()
+	}
+	s.backlog = backlog
+}
+
+func (s *relayState) crashed(err error, failures int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.lastErr = err
+	s.crashes++
+	s.failures = failures
+}
+
+func (s *relayState) restarted() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.restarts++
+}
+
+func (r *relay) Status() *RelayStatus {
+	s := &r.state
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	st := &RelayStatus{
+		Name:       r.cfg.Name,
+		Src:        r.cfg.Src.Address,
+		Dst:        r.cfg.Dst.Address,
+		State:      r.ctl.state(),
+		Shadow:     r.ctl.isShadow(),
+		Standby:    r.ctl.isStandby(),
+		Subscribed: s.subscribed,
+		SrcHeight:  s.srcHeight,
+		Backlog:    s.backlog,
+		Restarts:   s.restarts,
+		Crashes:    s.crashes,
+		Failures:   s.failures,
+		Held:       r.pol.heldMessage(),
+		Runway:     r.rw.status(),
+	}
+	if st.State == RelayStateRunning && r.rw.isBelowFloor() {
+		st.State = RelayStateLowBalance
+	}
+	if s.link != nil {
+		link := *s.link
+		st.Link = &link
+	}
+	if !s.pendingSince.IsZero() {
+		st.Lag = time.Since(s.pendingSince).Seconds()
+	}
+	if s.lastErr != nil {
+		st.LastError = s.lastErr.Error()
+	}
+	return st
+}
@@ -0,0 +1,45 @@
+package relay
+
+import (
+	"context"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/chain"
+)
+
+// subscription ...
+// is a cancellable chain.Receiver subscription, so that a relay can
+// resubscribe from a different height without restarting itself
+type subscription struct {
+	msgCh  chan *chain.Message
+	errCh  <-chan error
+	cancel context.CancelFunc
+}
+
+func (r *relay) subscribe(ctx context.Context, opts chain.SubscribeOptions) (*subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	msgCh := make(chan *chain.Message)
+	errCh, err := r.src.Subscribe(ctx, msgCh, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &subscription{msgCh: msgCh, errCh: errCh, cancel: cancel}, nil
+}
+
+// close ...
+// cancels the subscription and drains its channels until the receiver
+// closes "errCh", so that it's never left blocked on a send
+func (s *subscription) close() {
+	s.cancel()
+	go func() {
+		for {
+			select {
+			case <-s.msgCh:
+			case _, ok := <-s.errCh:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+}
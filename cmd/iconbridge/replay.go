@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/icon-project/icon-bridge/cmd/iconbridge/relay"
+	"github.com/icon-project/icon-bridge/common/log"
+)
+
+// runReplay ...
+// rebuilds the relay messages of a source height range with the receiver
+// of a configured relay, prints them, and sends them through the relay's
+// sender once confirmed; the messages the relay policy would hold are left
+// out, unless forced
+func runReplay(cfg *Config, args []string, l log.Logger) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	name := fs.String("relay", "", "relay name")
+	fromHeight := fs.Uint64("from", 0, "first source height")
+	toHeight := fs.Uint64("to", 0, "last source height")
+	fromSeq := fs.Uint64("seq-from", 0, "first event sequence, the one following the last delivered if zero")
+	toSeq := fs.Uint64("seq-to", 0, "last event sequence")
+	timeout := fs.Duration("timeout", 0, "source scan timeout")
+	send := fs.Bool("send", false, "send the relay txs to the destination")
+	yes := fs.Bool("yes", false, "send without confirmation")
+	force := fs.Bool("force", false, "send the messages the relay policy would hold too")
+	fs.Parse(args)
+
+	var rc *relay.RelayConfig
+	for _, c := range cfg.Relays {
+		if c.Name == *name {
+			rc = c
+		}
+	}
+	if rc == nil {
+		return fmt.Errorf("relay not found: %q", *name)
+	}
+	if *fromHeight == 0 || *toHeight == 0 {
+		return fmt.Errorf("source height range is required: -from and -to")
+	}
+
+	rp, err := relay.NewReplay(rc, l)
+	if err != nil {
+		return err
+	}
+	defer rp.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	link, err := rp.Status(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("link: rxSeq=%d rxHeight=%d currentHeight=%d\n", link.RxSeq, link.RxHeight, link.CurrentHeight)
+	if *fromSeq != 0 && *fromSeq != link.RxSeq+1 {
+		fmt.Printf("warning: seq %d doesn't follow the last delivered seq %d, the destination may reject it\n",
+			*fromSeq, link.RxSeq)
+	}
+
+	msg, err := rp.Collect(ctx, relay.ReplayOptions{
+		FromHeight: *fromHeight,
+		ToHeight:   *toHeight,
+		FromSeq:    *fromSeq,
+		ToSeq:      *toSeq,
+		Timeout:    *timeout,
+	})
+	if err != nil {
+		return err
+	}
+	note := ""
+	if allowed, held := rp.Check(msg); held != nil {
+		fmt.Printf("policy: seq %d held: %s\n", held.Seq, held.Reason)
+		if *force {
+			note = fmt.Sprintf(" (forced past seq %d held by the policy: %s)", held.Seq, held.Reason)
+		} else if len(allowed.Receipts) == 0 {
+			return fmt.Errorf("seq %d held by the relay policy: %s; use -force to replay it", held.Seq, held.Reason)
+		} else {
+			msg = allowed
+			note = fmt.Sprintf(" (up to seq %d, seq %d held by the policy: %s)", held.Seq-1, held.Seq, held.Reason)
+		}
+	}
+	txs, err := rp.Segment(ctx, msg)
+	if err != nil {
+		return err
+	}
+	for i, tx := range txs {
+		b, err := json.Marshal(tx.RelayTx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("tx %d: seq=[%d %d] receipts=%d size=%d\n%s\n", i+1, tx.SeqBegin, tx.SeqEnd, tx.Receipts, tx.Size(), b)
+	}
+	if !*send || len(txs) == 0 {
+		return nil
+	}
+
+	if !*yes {
+		fmt.Printf("send %d relay txs to %s%s? [y/N] ", len(txs), rc.Dst.Address, note)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return nil
+		}
+	}
+	for i, tx := range txs {
+		start := time.Now()
+		height, err := rp.Send(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("tx %d: %v", i+1, err)
+		}
+		fmt.Printf("tx %d: id=%v delivered at height %d in %v\n", i+1, tx.ID(), height, time.Since(start).Truncate(time.Millisecond))
+	}
+	return nil
+}
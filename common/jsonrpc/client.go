@@ -30,6 +30,12 @@ func NewJsonRpcClient(hc *http.Client, endpoint string) *Client {
 	return &Client{hc: hc, Endpoint: endpoint, CustomHeader: make(map[string]string)}
 }
 
+// CloseIdleConnections ...
+// closes the idle keep-alive connections to the endpoint
+func (c *Client) CloseIdleConnections() {
+	c.hc.CloseIdleConnections()
+}
+
 func (c *Client) _do(req *http.Request) (resp *http.Response, err error) {
 	if c.Pre != nil {
 		if err = c.Pre(req); err != nil {